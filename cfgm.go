@@ -2,6 +2,8 @@ package cfgm
 
 import (
 	"github.com/SnowPhoenix0105/cfgm/pkg/controller"
+	"io"
+	"time"
 )
 
 var defaultContext = controller.NewConfigManageContext(&controller.ConfigManageContextOptions{
@@ -13,10 +15,34 @@ func Register(path string, ptrToConfigObject interface{}, callback controller.Co
 	defaultContext.Register(path, ptrToConfigObject, callback)
 }
 
-func Get(path string, ptr interface{}) bool {
+func Get(path string, ptr interface{}) (bool, error) {
 	return defaultContext.Get(path, ptr)
 }
 
 func Init() []error {
 	return defaultContext.Init()
 }
+
+func DumpTemplate(writer io.Writer, format string) error {
+	return defaultContext.DumpTemplate(writer, format)
+}
+
+func DumpTemplateWithLevel(writer io.Writer, format string, level string) error {
+	return defaultContext.DumpTemplateWithLevel(writer, format, level)
+}
+
+func Reload() []error {
+	return defaultContext.Reload()
+}
+
+func Watch(interval time.Duration, handler func(errs []error)) (stop func()) {
+	return defaultContext.Watch(interval, handler)
+}
+
+func Explain(path string) (*controller.Explanation, error) {
+	return defaultContext.Explain(path)
+}
+
+func DumpEffective(writer io.Writer) error {
+	return defaultContext.DumpEffective(writer)
+}
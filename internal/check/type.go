@@ -1,7 +1,33 @@
 package check
 
-import "reflect"
+import (
+	"encoding"
+	"net/url"
+	"reflect"
+	"time"
+)
+
+var (
+	DurationType        = reflect.TypeOf(time.Duration(0))
+	TimeType            = reflect.TypeOf(time.Time{})
+	URLType             = reflect.TypeOf(url.URL{})
+	textMarshalerType   = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+	textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+)
 
 func IsPtr(obj interface{}) bool {
 	return reflect.TypeOf(obj).Kind() == reflect.Ptr
 }
+
+/*
+IsTextType reports whether typ or the pointer to it implements encoding.TextMarshaler or
+encoding.TextUnmarshaler, whose values are strings in the tree. It is shared by building, refilling
+and promoting the embedded structs, so that all of them agree on which types are strings.
+*/
+func IsTextType(typ reflect.Type) bool {
+	if typ.Kind() == reflect.Interface {
+		return false
+	}
+	ptr := reflect.PtrTo(typ)
+	return typ.Implements(textMarshalerType) || ptr.Implements(textMarshalerType) || ptr.Implements(textUnmarshalerType)
+}
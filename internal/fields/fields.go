@@ -0,0 +1,188 @@
+package fields
+
+import (
+	"github.com/SnowPhoenix0105/cfgm/internal/check"
+	"reflect"
+	"sort"
+	"sync"
+)
+
+// Field is a field of struct, which may be promoted from an embedded struct.
+type Field struct {
+	// Name is the key of the field in the tree.
+	Name string
+
+	// Index is the index sequence for reflect.Value.FieldByIndex.
+	Index []int
+
+	// StructField is the declaration of the field, where the tags are read from.
+	StructField reflect.StructField
+}
+
+type cacheKey struct {
+	typ    reflect.Type
+	naming Naming
+}
+
+var (
+	cache sync.Map // map[cacheKey][]Field
+)
+
+/*
+Of returns the fields of the struct type typ in the order of declaration, and the fields of the
+embedded structs and the embedded pointers to structs are promoted like encoding/json does.
+
+The name of a field is given by the "cfgm" tag, e.g. `cfgm:"pool_size"`, or converted from the
+name of declaration with naming, and the fields tagged `cfgm:"-"` are skipped. The embedded structs
+with names in the tags are not promoted but named fields.
+
+A name shadows the same names in the deeper embedded structs. The same names at the same depth are
+all dropped since none of them dominates the others, unless only one of them is named by the tag.
+The embedded structs implementing encoding.TextMarshaler or encoding.TextUnmarshaler are not
+promoted, e.g. time.Time, and neither is url.URL, which is a string too.
+*/
+func Of(typ reflect.Type, naming Naming) []Field {
+	key := cacheKey{typ: typ, naming: naming}
+	if fields, ok := cache.Load(key); ok {
+		return fields.([]Field)
+	}
+	fields, _ := cache.LoadOrStore(key, typeFields(typ, naming))
+	return fields.([]Field)
+}
+
+type embedded struct {
+	typ   reflect.Type
+	index []int
+}
+
+type candidate struct {
+	Field
+	depth  int
+	tagged bool
+}
+
+func typeFields(typ reflect.Type, naming Naming) []Field {
+	candidates := make(map[string][]candidate)
+
+	next := []embedded{{typ: typ}}
+	// the depths where the types are expanded, the same type embedded twice at the same depth is
+	// expanded twice, so that its fields are dropped as the same names
+	visited := make(map[reflect.Type]int)
+	for depth := 0; len(next) != 0; depth++ {
+		current := next
+		next = nil
+		for _, e := range current {
+			if visitedDepth, ok := visited[e.typ]; ok && visitedDepth < depth {
+				continue
+			}
+			visited[e.typ] = depth
+			for i := 0; i < e.typ.NumField(); i++ {
+				field := e.typ.Field(i)
+				name, _ := ParseTag(field.Tag.Get(tagKey))
+				if name == "-" {
+					continue
+				}
+				index := make([]int, len(e.index)+1)
+				copy(index, e.index)
+				index[len(e.index)] = i
+
+				tagged := len(name) != 0
+				if typ, ok := promoted(field); ok && !tagged {
+					next = append(next, embedded{typ: typ, index: index})
+					continue
+				}
+				if !tagged {
+					name = naming.Name(field.Name)
+				}
+				if shallower := candidates[name]; len(shallower) != 0 && shallower[0].depth < depth {
+					// shadowed
+					continue
+				}
+				candidates[name] = append(candidates[name], candidate{
+					Field:  Field{Name: name, Index: index, StructField: field},
+					depth:  depth,
+					tagged: tagged,
+				})
+			}
+		}
+	}
+
+	dominant := make([]Field, 0, len(candidates))
+	for _, same := range candidates {
+		if field, ok := dominate(same); ok {
+			dominant = append(dominant, field)
+		}
+	}
+	sort.Slice(dominant, func(i, j int) bool {
+		return lessIndex(dominant[i].Index, dominant[j].Index)
+	})
+	return dominant
+}
+
+// dominate returns the only field of the same name at the same depth, or the only tagged one.
+func dominate(same []candidate) (Field, bool) {
+	if len(same) == 1 {
+		return same[0].Field, true
+	}
+	var ret *candidate
+	for i := range same {
+		if !same[i].tagged {
+			continue
+		}
+		if ret != nil {
+			return Field{}, false
+		}
+		ret = &same[i]
+	}
+	if ret == nil {
+		return Field{}, false
+	}
+	return ret.Field, true
+}
+
+// promoted reports whether the fields of field are promoted, and returns the struct type of it.
+func promoted(field reflect.StructField) (reflect.Type, bool) {
+	if !field.Anonymous {
+		return nil, false
+	}
+	typ := field.Type
+	if typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+	}
+	if typ.Kind() != reflect.Struct {
+		return nil, false
+	}
+	if check.IsTextType(typ) || typ == check.URLType {
+		return nil, false
+	}
+	return typ, true
+}
+
+func lessIndex(left, right []int) bool {
+	for i := 0; i < len(left) && i < len(right); i++ {
+		if left[i] != right[i] {
+			return left[i] < right[i]
+		}
+	}
+	return len(left) < len(right)
+}
+
+/*
+ByIndex returns the field of the struct value obj at index, and false if an embedded pointer on
+the way is nil. The nil pointers are allocated if alloc is true and they can be set.
+*/
+func ByIndex(obj reflect.Value, index []int, alloc bool) (reflect.Value, bool) {
+	for i, x := range index {
+		if i > 0 && obj.Kind() == reflect.Ptr {
+			if obj.IsNil() {
+				if !alloc || !obj.CanSet() {
+					return reflect.Value{}, false
+				}
+				obj.Set(reflect.New(obj.Type().Elem()))
+			}
+			obj = obj.Elem()
+		}
+		obj = obj.Field(x)
+	}
+	return obj, true
+}
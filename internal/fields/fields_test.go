@@ -0,0 +1,169 @@
+package fields
+
+import (
+	"github.com/stretchr/testify/assert"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func names(typ reflect.Type) []string {
+	return namesWith(typ, NamingExact)
+}
+
+func namesWith(typ reflect.Type, naming Naming) []string {
+	ret := make([]string, 0)
+	for _, field := range Of(typ, naming) {
+		ret = append(ret, field.Name)
+	}
+	return ret
+}
+
+func TestOf(t *testing.T) {
+	type Base struct {
+		ID   int
+		Name string
+	}
+	type Meta struct {
+		Name    string
+		Version int
+	}
+	type Audit struct {
+		Version int
+		Created time.Time
+	}
+	type Resource struct {
+		Base
+		*Meta
+		Audit
+		Kind string
+		time.Time
+	}
+
+	// Name and Version are ambiguous at the same depth
+	assert.Equal(t, []string{"ID", "Created", "Kind", "Time"}, names(reflect.TypeOf(Resource{})))
+
+	type Shadow struct {
+		Base
+		Name string
+	}
+	fields := Of(reflect.TypeOf(Shadow{}), NamingExact)
+	assert.Equal(t, []string{"ID", "Name"}, names(reflect.TypeOf(Shadow{})))
+	assert.Equal(t, []int{0, 0}, fields[0].Index)
+	assert.Equal(t, []int{1}, fields[1].Index)
+
+	type Nested struct {
+		Shadow
+		ID string
+	}
+	fields = Of(reflect.TypeOf(Nested{}), NamingExact)
+	assert.Equal(t, []string{"Name", "ID"}, names(reflect.TypeOf(Nested{})))
+	assert.Equal(t, []int{0, 1}, fields[0].Index)
+	assert.Equal(t, reflect.TypeOf(""), fields[1].StructField.Type)
+}
+
+func TestOf_Naming(t *testing.T) {
+	type Base struct {
+		ID      int
+		Version int
+	}
+	type Server struct {
+		Base
+		HTTPServer  string
+		PoolSize    int    `cfgm:"pool"`
+		MaxIdleTime int    `cfgm:",required"`
+		Internal    string `cfgm:"-"`
+		Ver         int    `cfgm:"Version"`
+		Named       Base   `cfgm:"base"`
+	}
+	typ := reflect.TypeOf(Server{})
+
+	// Ver is tagged with the same name as Base.Version, and shadows it at a shallower depth, while
+	// the converted names of Base.Version are different from the tag
+	assert.Equal(t, []string{"ID", "HTTPServer", "pool", "MaxIdleTime", "Version", "base"}, namesWith(typ, NamingExact))
+	assert.Equal(t, []string{"id", "version", "http_server", "pool", "max_idle_time", "Version", "base"}, namesWith(typ, NamingSnakeCase))
+	assert.Equal(t, []string{"id", "version", "http-server", "pool", "max-idle-time", "Version", "base"}, namesWith(typ, NamingKebabCase))
+	assert.Equal(t, []string{"id", "version", "httpServer", "pool", "maxIdleTime", "Version", "base"}, namesWith(typ, NamingCamelCase))
+
+	type Left struct {
+		Name string `cfgm:"name"`
+	}
+	type Right struct {
+		Name string
+	}
+	type Both struct {
+		Left
+		Right
+	}
+	// the tagged one dominates the same name at the same depth
+	fields := Of(reflect.TypeOf(Both{}), NamingSnakeCase)
+	assert.Equal(t, 1, len(fields))
+	assert.Equal(t, []int{0, 0}, fields[0].Index)
+	assert.Equal(t, []string{"name", "Name"}, namesWith(reflect.TypeOf(Both{}), NamingExact))
+}
+
+func TestNaming_Name(t *testing.T) {
+	cases := []struct {
+		name  string
+		snake string
+		kebab string
+		camel string
+	}{
+		{"PoolSize", "pool_size", "pool-size", "poolSize"},
+		{"HTTPServer", "http_server", "http-server", "httpServer"},
+		{"UserID", "user_id", "user-id", "userId"},
+		{"Level2Cache", "level2_cache", "level2-cache", "level2Cache"},
+		{"Max_Idle", "max_idle", "max-idle", "maxIdle"},
+		{"URL", "url", "url", "url"},
+		{"name", "name", "name", "name"},
+	}
+	for _, c := range cases {
+		assert.Equal(t, c.name, NamingExact.Name(c.name))
+		assert.Equal(t, c.snake, NamingSnakeCase.Name(c.name))
+		assert.Equal(t, c.kebab, NamingKebabCase.Name(c.name))
+		assert.Equal(t, c.camel, NamingCamelCase.Name(c.name))
+	}
+}
+
+func TestParseTag(t *testing.T) {
+	cases := []struct {
+		tag  string
+		name string
+		rest string
+	}{
+		{"", "", ""},
+		{"-", "-", ""},
+		{"pool_size", "pool_size", ""},
+		{"pool_size,required,min=1", "pool_size", "required,min=1"},
+		{",required", "", "required"},
+		{"required,min=1", "", "required,min=1"},
+		{"lenient", "", "lenient"},
+		{"pattern=^a,b$", "", "pattern=^a,b$"},
+		{"name,pattern=^a,b$", "name", "pattern=^a,b$"},
+	}
+	for _, c := range cases {
+		name, rest := ParseTag(c.tag)
+		assert.Equal(t, c.name, name, c.tag)
+		assert.Equal(t, c.rest, rest, c.tag)
+	}
+}
+
+func TestByIndex(t *testing.T) {
+	type Meta struct {
+		Name string
+	}
+	type Resource struct {
+		*Meta
+	}
+	obj := Resource{}
+	value := reflect.ValueOf(&obj).Elem()
+
+	_, ok := ByIndex(value, []int{0, 0}, false)
+	assert.False(t, ok)
+	assert.Nil(t, obj.Meta)
+
+	field, ok := ByIndex(value, []int{0, 0}, true)
+	assert.True(t, ok)
+	field.SetString("name")
+	assert.Equal(t, &Meta{Name: "name"}, obj.Meta)
+}
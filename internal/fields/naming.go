@@ -0,0 +1,128 @@
+package fields
+
+import (
+	"strings"
+	"unicode"
+)
+
+type Naming int
+
+const (
+	// NamingExact keeps the names of fields, e.g. "PoolSize".
+	NamingExact Naming = iota
+	// NamingSnakeCase converts the names of fields to snake_case, e.g. "pool_size".
+	NamingSnakeCase
+	// NamingKebabCase converts the names of fields to kebab-case, e.g. "pool-size".
+	NamingKebabCase
+	// NamingCamelCase converts the names of fields to camelCase, e.g. "poolSize".
+	NamingCamelCase
+)
+
+// Name converts the name of a field with naming, the acronyms are kept as words, e.g. "HTTPServer"
+// is "http_server", "http-server" or "httpServer".
+func (naming Naming) Name(name string) string {
+	switch naming {
+	case NamingSnakeCase:
+		return strings.ToLower(strings.Join(words(name), "_"))
+	case NamingKebabCase:
+		return strings.ToLower(strings.Join(words(name), "-"))
+	case NamingCamelCase:
+		builder := strings.Builder{}
+		for i, word := range words(name) {
+			word = strings.ToLower(word)
+			if i != 0 {
+				runes := []rune(word)
+				runes[0] = unicode.ToUpper(runes[0])
+				word = string(runes)
+			}
+			builder.WriteString(word)
+		}
+		return builder.String()
+	}
+	return name
+}
+
+/*
+words splits an identifier into words. A word begins at an upper case letter following a lower
+case letter or a digit, or at the last upper case letter of an acronym followed by a lower case
+letter, and '_' separates the words too, e.g.
+
+	"PoolSize"    -> "Pool", "Size"
+	"HTTPServer"  -> "HTTP", "Server"
+	"Level2Cache" -> "Level2", "Cache"
+	"Max_Idle"    -> "Max", "Idle"
+*/
+func words(name string) []string {
+	ret := make([]string, 0)
+	runes := []rune(name)
+	begin := 0
+	for i, r := range runes {
+		if r == '_' {
+			if i > begin {
+				ret = append(ret, string(runes[begin:i]))
+			}
+			begin = i + 1
+			continue
+		}
+		if i == begin || !unicode.IsUpper(r) {
+			continue
+		}
+		prev := runes[i-1]
+		if unicode.IsLower(prev) || unicode.IsDigit(prev) ||
+			(unicode.IsUpper(prev) && i+1 < len(runes) && unicode.IsLower(runes[i+1])) {
+			ret = append(ret, string(runes[begin:i]))
+			begin = i
+		}
+	}
+	if begin < len(runes) {
+		ret = append(ret, string(runes[begin:]))
+	}
+	return ret
+}
+
+const tagKey = "cfgm"
+
+// SecretTag marks a field as secret with `secret:"true"`, whose value is redacted in the outputs.
+const SecretTag = "secret"
+
+// The keywords of the "cfgm" tag, which cannot be the names of fields.
+const (
+	// OptionStrict and OptionLenient override the mode of refilling, see tree2obj.Options.
+	OptionStrict  = "strict"
+	OptionLenient = "lenient"
+	// RuleRequired is the only rule without '=', see validate.Validate.
+	RuleRequired = "required"
+)
+
+// IsOption reports whether item of a "cfgm" tag is an option of refilling rather than a rule.
+func IsOption(item string) bool {
+	return item == OptionStrict || item == OptionLenient
+}
+
+/*
+ParseTag splits a "cfgm" tag into the name of the field and the rest of items, e.g. "pool_size"
+and "required,min=1" of `cfgm:"pool_size,required,min=1"`. The first item is the name unless it is
+empty, a rule with '=' or a keyword like "required" and "lenient", so the tags without names, e.g.
+`cfgm:"required"`, are still valid. The tag `cfgm:"-"` skips the field, whose name is "-".
+*/
+func ParseTag(tag string) (name string, rest string) {
+	if tag == "-" {
+		return tag, ""
+	}
+	first, rest := tag, ""
+	if index := strings.IndexByte(tag, ','); index >= 0 {
+		first, rest = tag[:index], tag[index+1:]
+	}
+	first = strings.TrimSpace(first)
+	if len(first) == 0 {
+		return "", rest
+	}
+	if strings.IndexByte(first, '=') >= 0 || isKeyword(first) {
+		return "", tag
+	}
+	return first, rest
+}
+
+func isKeyword(item string) bool {
+	return item == RuleRequired || IsOption(item)
+}
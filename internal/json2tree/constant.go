@@ -3,6 +3,9 @@ package json2tree
 const DEBUG = true
 const DEBUG_ENABLE_PARSER_LOG = false
 
+// includeKey is the reserved key to include other files, see MergeWithOptions.
+const includeKey = "$include"
+
 type emptyType struct{}
 
 var emptyValue = emptyType{}
@@ -1,6 +1,9 @@
 package json2tree
 
-import "fmt"
+import (
+	"fmt"
+	"strings"
+)
 
 // <<<==== lexer error begin ====>>>
 
@@ -63,3 +66,24 @@ func (e *DuplicateKeyError) Error() string {
 }
 
 // <<----- parser error end ----->>
+
+// <<<==== include error begin ====>>>
+
+/*
+IncludeError describes an error in an included file, Chain is the files from the outermost one
+to the file where the error occurs.
+*/
+type IncludeError struct {
+	Chain []string
+	Inner error
+}
+
+func (e *IncludeError) Error() string {
+	return fmt.Sprintf("include error in %s: %s", strings.Join(e.Chain, " -> "), e.Inner.Error())
+}
+
+func (e *IncludeError) Unwrap() error {
+	return e.Inner
+}
+
+// <<----- include error end ----->>
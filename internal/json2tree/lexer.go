@@ -35,6 +35,7 @@ const (
 	TokenComma                 // ','
 	TokenColon                 // ':'
 	TokenInt
+	TokenUint // the integers above math.MaxInt64
 	TokenFloat
 	TokenBool
 	TokenNull
@@ -62,6 +63,8 @@ func (typ TokenType) String() string {
 		return "TokenColon"
 	case TokenInt:
 		return "TokenInt"
+	case TokenUint:
+		return "TokenUint"
 	case TokenFloat:
 		return "TokenFloat"
 	case TokenBool:
@@ -91,6 +94,7 @@ type lexer struct {
 	// results
 	currentString strings.Builder
 	currentInt    int64
+	currentUint   uint64
 	currentFloat  float64
 	currentBool   bool
 
@@ -130,6 +134,10 @@ func (lex *lexer) Int() int64 {
 	return lex.currentInt
 }
 
+func (lex *lexer) Uint() uint64 {
+	return lex.currentUint
+}
+
 func (lex *lexer) Float() float64 {
 	return lex.currentFloat
 }
@@ -330,12 +338,18 @@ func (lex *lexer) parseNumber() (err error) {
 			return err
 		}
 		lex.currentToken = TokenFloat
-		lex.getChar()
+		// lex.getChar() currentChar is already not a part of number
 		return nil
 	}
 	lex.currentInt, err = strconv.ParseInt(buffer.String(), 10, 64)
 	if err != nil {
-		return err
+		var uintErr error
+		lex.currentUint, uintErr = strconv.ParseUint(buffer.String(), 10, 64)
+		if uintErr != nil {
+			return err
+		}
+		lex.currentToken = TokenUint
+		return nil
 	}
 	lex.currentToken = TokenInt
 	// lex.getChar() currentChar is already not a part of number
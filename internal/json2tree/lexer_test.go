@@ -40,6 +40,20 @@ func TestIntegerToken(t *testing.T) {
 	assert.Equal(expect, lex.Int())
 }
 
+func TestUintToken(t *testing.T) {
+	assert := assertions.New(t)
+
+	origin := `18446744073709551615`
+	expect := uint64(18446744073709551615)
+	lex := lexer{}
+	lex.Reset(strings.NewReader(origin))
+	assert.True(lex.HasNext())
+	typ, err := lex.Next()
+	assert.Nil(err)
+	assert.Equal(TokenUint, typ)
+	assert.Equal(expect, lex.Uint())
+}
+
 func TestFloatToken(t *testing.T) {
 	assert := assertions.New(t)
 
@@ -54,6 +68,19 @@ func TestFloatToken(t *testing.T) {
 	assert.Equal(expect, lex.Float())
 }
 
+func TestFloatTokenFollowed(t *testing.T) {
+	assert := assertions.New(t)
+
+	origin := `[1.5]`
+	lex := lexer{}
+	lex.Reset(strings.NewReader(origin))
+	for _, expect := range []TokenType{TokenLeftSquare, TokenFloat, TokenRightSquare} {
+		typ, err := lex.Next()
+		assert.Nil(err)
+		assert.Equal(expect, typ)
+	}
+}
+
 func TestBoolToken(t *testing.T) {
 	assert := assertions.New(t)
 
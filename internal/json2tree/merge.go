@@ -12,8 +12,46 @@ func MergeString(root *tree.Node, json string, time tree.ModifyTime) error {
 }
 
 func Merge(root *tree.Node, reader io.RuneReader, time tree.ModifyTime) error {
+	return MergeWithFileName(root, reader, time, "")
+}
+
+/*
+MergeWithFileName works like Merge, and records the position of each key and element in the file
+as the source of its node, e.g. "config.json:3:5".
+*/
+func MergeWithFileName(root *tree.Node, reader io.RuneReader, time tree.ModifyTime, fileName string) error {
+	return MergeWithOptions(root, reader, time, &Options{FileName: fileName})
+}
+
+type Options struct {
+	// FileName is recorded in the sources of nodes, and the included files are relative to it.
+	FileName string
+
+	// Include returns the files matching a pattern in the "$include" of fileName, the files
+	// are merged in order. "$include" is not supported if it is nil.
+	Include func(fileName, pattern string) ([]string, error)
+
+	// Open opens an included file.
+	Open func(name string) (io.ReadCloser, error)
+}
+
+/*
+MergeWithOptions works like MergeWithFileName, and supports the "$include" key, which must be the
+first key of an object, and its value is a pattern or a list of patterns of files, e.g.
+`"$include": ["common.json", "db/*.json"]`. The included files are merged into the object in order
+before the other keys of the object, so the including file covers the included ones.
+
+Errors:
+
+	IncludeError: an included file cannot be found or merged, or the files include each other
+	the errors of MergeWithFileName
+*/
+func MergeWithOptions(root *tree.Node, reader io.RuneReader, time tree.ModifyTime, options *Options) error {
 	par := parser{
-		walker: tree.WriteFrom(root, time),
+		walker:   tree.WriteFrom(root, time),
+		fileName: options.FileName,
+		options:  options,
+		chain:    []string{options.FileName},
 	}
 	err := par.Reset(reader)
 	if err != nil {
@@ -1,9 +1,12 @@
 package json2tree
 
 import (
+	"bufio"
+	"errors"
 	"fmt"
 	"github.com/SnowPhoenix0105/cfgm/internal/tree"
 	"io"
+	"path/filepath"
 	"strconv"
 	"strings"
 )
@@ -12,6 +15,10 @@ type parser struct {
 	lex        lexer
 	innerError error
 	walker     tree.Walker
+	fileName   string
+	options    *Options
+	// chain is the files including this file, and this file at last
+	chain []string
 }
 
 func (env *parser) Reset(reader io.RuneReader) error {
@@ -90,6 +97,8 @@ func (env *parser) unexpectError() error {
 		}
 	case TokenInt:
 		content = strconv.FormatInt(env.lex.Int(), 10)
+	case TokenUint:
+		content = strconv.FormatUint(env.lex.Uint(), 10)
 	case TokenFloat:
 		content = strconv.FormatFloat(env.lex.Float(), 'f', 10, 64)
 	case TokenNull:
@@ -121,6 +130,7 @@ func (env *parser) unexpectError() error {
 func (env *parser) inFirstSetForNode() bool {
 	switch env.tokenType() {
 	case TokenInt,
+		TokenUint,
 		TokenString,
 		TokenBool,
 		TokenFloat,
@@ -137,6 +147,7 @@ func (env *parser) parseNode() error {
 	if DEBUG {
 		env.assertType("Node",
 			TokenInt,
+			TokenUint,
 			TokenString,
 			TokenBool,
 			TokenFloat,
@@ -152,15 +163,27 @@ func (env *parser) parseNode() error {
 	case TokenInt:
 		integer := env.lex.Int()
 		env.getToken()
+		tree.KeepValues(env.walker, tree.NodeKeyInt, tree.NodeKeyFloat)
 		env.walker.SetInt(integer)
 		env.walker.SetNullFor(tree.NodeKeyInt, false)
 		env.walker.SetFloat(float64(integer))
 		env.walker.SetNullFor(tree.NodeKeyFloat, false)
 		return nil
 
+	case TokenUint:
+		integer := env.lex.Uint()
+		env.getToken()
+		tree.KeepValues(env.walker, tree.NodeKeyUint, tree.NodeKeyFloat)
+		env.walker.SetUint(integer)
+		env.walker.SetNullFor(tree.NodeKeyUint, false)
+		env.walker.SetFloat(float64(integer))
+		env.walker.SetNullFor(tree.NodeKeyFloat, false)
+		return nil
+
 	case TokenFloat:
 		f := env.lex.Float()
 		env.getToken()
+		tree.KeepValues(env.walker, tree.NodeKeyFloat)
 		env.walker.SetFloat(f)
 		env.walker.SetNullFor(tree.NodeKeyFloat, false)
 		return nil
@@ -168,6 +191,7 @@ func (env *parser) parseNode() error {
 	case TokenBool:
 		b := env.lex.Bool()
 		env.getToken()
+		tree.KeepValues(env.walker, tree.NodeKeyBool)
 		env.walker.SetBool(b)
 		env.walker.SetNullFor(tree.NodeKeyBool, false)
 		return nil
@@ -175,18 +199,21 @@ func (env *parser) parseNode() error {
 	case TokenString:
 		str := env.lex.String()
 		env.getToken()
+		tree.KeepValues(env.walker, tree.NodeKeyString)
 		env.walker.SetString(str)
 		env.walker.SetNullFor(tree.NodeKeyString, false)
 
 		return nil
 	case TokenNull:
 		env.getToken()
-		setNull(env.walker)
+		tree.SetNull(env.walker)
 		return nil
 	case TokenLeftBrace:
+		tree.KeepValues(env.walker, tree.NodeKeyObj)
 		return env.parseObject()
 
 	case TokenLeftSquare:
+		tree.KeepValues(env.walker, tree.NodeKeyList)
 		return env.parseList()
 
 	default:
@@ -231,10 +258,19 @@ func (env *parser) parseKvPairs() error {
 		}
 	}
 	keySet := make(map[string]emptyType)
+	if env.tokenType() == TokenString && env.lex.String() == includeKey && env.options.Include != nil {
+		err := env.parseInclude()
+		if err != nil {
+			return err
+		}
+	}
 	for env.tokenType() == TokenString {
+		if env.lex.String() == includeKey && env.options.Include != nil {
+			return env.includeError(errors.New(includeKey + " must be the first key of the object"))
+		}
 		key, err := env.parseKvPair()
 		if err != nil {
-			return nil
+			return err
 		}
 		_, ok := keySet[key]
 		if ok {
@@ -258,6 +294,7 @@ func (env *parser) parseKvPair() (string, error) {
 		}
 	}
 	key := env.lex.String()
+	line, row := env.lex.StartAt()
 	if env.getToken() == TokenInvalid {
 		return "", env.lexerError()
 	}
@@ -275,6 +312,7 @@ func (env *parser) parseKvPair() (string, error) {
 		return "", env.unexpectError()
 	}
 	env.walker.EnterObj(key)
+	env.walker.SetSource(tree.FilePosition(env.fileName, line, row))
 	err := env.parseNode()
 	env.walker.Exit()
 	if err != nil {
@@ -288,6 +326,110 @@ func (env *parser) parseKvPair() (string, error) {
 	return key, nil
 }
 
+// <<<==== include begin ====>>>
+
+func (env *parser) includeError(err error) error {
+	if _, ok := err.(*IncludeError); ok {
+		// from the included file, whose chain is longer
+		return err
+	}
+	chain := make([]string, len(env.chain))
+	copy(chain, env.chain)
+	return &IncludeError{Chain: chain, Inner: err}
+}
+
+// parseInclude parses the "$include" key and its patterns, then merges the included files.
+func (env *parser) parseInclude() error {
+	// string
+	if env.getToken() == TokenInvalid {
+		return env.lexerError()
+	}
+
+	// ':'
+	if env.tokenType() != TokenColon {
+		return env.unexpectError()
+	}
+	if env.getToken() == TokenInvalid {
+		return env.lexerError()
+	}
+
+	// string | '[' string* ']'
+	patterns := make([]string, 0)
+	switch env.tokenType() {
+	case TokenString:
+		patterns = append(patterns, env.lex.String())
+	case TokenLeftSquare:
+		env.getToken()
+		for env.tokenType() == TokenString {
+			patterns = append(patterns, env.lex.String())
+			// the patterns must be separated by ','
+			if env.getToken() != TokenComma {
+				break
+			}
+			env.getToken()
+		}
+		if env.tokenType() != TokenRightSquare {
+			return env.unexpectError()
+		}
+	default:
+		return env.unexpectError()
+	}
+	env.getToken()
+	if env.tokenType() == TokenComma {
+		// ','
+		env.getToken()
+	}
+
+	for _, pattern := range patterns {
+		files, err := env.options.Include(env.fileName, pattern)
+		if err != nil {
+			return env.includeError(err)
+		}
+		for _, file := range files {
+			err = env.mergeIncluded(file)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (env *parser) mergeIncluded(fileName string) error {
+	// the paths are cleaned, so that "./a.json" and "a.json" are the same file
+	for _, including := range env.chain {
+		if filepath.Clean(including) == filepath.Clean(fileName) {
+			return env.includeError(fmt.Errorf("%s is included cyclically", fileName))
+		}
+	}
+	chain := append(append(make([]string, 0, len(env.chain)+1), env.chain...), fileName)
+	file, err := env.options.Open(fileName)
+	if err != nil {
+		return env.includeError(err)
+	}
+	defer file.Close()
+
+	par := parser{
+		walker:   env.walker,
+		fileName: fileName,
+		options:  env.options,
+		chain:    chain,
+	}
+	err = par.Reset(bufio.NewReader(file))
+	if err == nil && par.tokenType() != TokenLeftBrace {
+		err = par.unexpectError()
+	}
+	if err == nil {
+		err = par.parseNode()
+	}
+	if err != nil {
+		return par.includeError(err)
+	}
+	return nil
+}
+
+// <<----- include end ----->>
+
 func (env *parser) parseList() error {
 	// '['
 	if DEBUG {
@@ -321,6 +463,7 @@ func (env *parser) parseElements() error {
 	if DEBUG {
 		env.assertType("Elements",
 			TokenInt,
+			TokenUint,
 			TokenString,
 			TokenBool,
 			TokenFloat,
@@ -334,7 +477,9 @@ func (env *parser) parseElements() error {
 	}
 	index := 0
 	for env.inFirstSetForNode() {
+		line, row := env.lex.StartAt()
 		env.walker.EnterList(index)
+		env.walker.SetSource(tree.FilePosition(env.fileName, line, row))
 		err := env.parseElement()
 		env.walker.Exit()
 		index++
@@ -350,6 +495,7 @@ func (env *parser) parseElement() error {
 	if DEBUG {
 		env.assertType("Element",
 			TokenInt,
+			TokenUint,
 			TokenString,
 			TokenBool,
 			TokenFloat,
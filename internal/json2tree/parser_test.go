@@ -2,8 +2,13 @@ package json2tree
 
 import (
 	"github.com/SnowPhoenix0105/cfgm/internal/obj2tree"
+	"github.com/SnowPhoenix0105/cfgm/internal/tree"
 	"github.com/SnowPhoenix0105/cfgm/internal/tree2json"
 	"github.com/stretchr/testify/assert"
+	"io"
+	"io/ioutil"
+	"path"
+	"sort"
 	"strings"
 	"testing"
 )
@@ -96,3 +101,79 @@ func TestMapPrototype(t *testing.T) {
 	str := tree2json.DumpToString(root)
 	t.Log(str)
 }
+
+func TestInclude(t *testing.T) {
+	files := map[string]string{
+		"app.json": `{
+	"$include": ["common.json", "db/*.json"],
+	"Name": "app"
+}`,
+		"common.json":    `{"Name": "common", "Debug": true}`,
+		"db/mysql.json":  `{"DB": {"$include": "../pool.json", "Host": "mysql"}}`,
+		"pool.json":      `{"Size": 4, "Host": "pool"}`,
+		"cycle.json":     `{"$include": "cycle2.json"}`,
+		"cycle2.json":    `{"$include": ["cycle.json"]}`,
+		"misplaced.json": `{"Name": "app", "$include": "common.json"}`,
+		"back.json":      `{"$include": "cycle3.json"}`,
+		"cycle3.json":    `{"$include": "back.json"}`,
+		"nocomma.json":   `{"$include": ["common.json" "pool.json"]}`,
+	}
+	options := func(fileName string) *Options {
+		return &Options{
+			FileName: fileName,
+			Include: func(fileName, pattern string) ([]string, error) {
+				pattern = path.Join(path.Dir(fileName), pattern)
+				matches := make([]string, 0)
+				for name := range files {
+					if ok, _ := path.Match(pattern, name); ok {
+						matches = append(matches, name)
+					}
+				}
+				sort.Strings(matches)
+				return matches, nil
+			},
+			Open: func(name string) (io.ReadCloser, error) {
+				return ioutil.NopCloser(strings.NewReader(files[name])), nil
+			},
+		}
+	}
+
+	root := tree.NewNode()
+	err := MergeWithOptions(root, strings.NewReader(files["app.json"]), 1, options("app.json"))
+	assert.Nil(t, err)
+	obj := root.Obj()
+	assert.Equal(t, "app", obj["Name"].String())
+	assert.Equal(t, "app.json:3:2", obj["Name"].Source())
+	assert.True(t, obj["Debug"].Bool())
+	db := obj["DB"].Obj()
+	assert.Equal(t, "mysql", db["Host"].String())
+	assert.Equal(t, int64(4), db["Size"].Int())
+	assert.Equal(t, "pool.json:1:2", db["Size"].Source())
+	_, ok := obj["$include"]
+	assert.False(t, ok)
+
+	err = MergeWithOptions(tree.NewNode(), strings.NewReader(files["cycle.json"]), 1, options("cycle.json"))
+	assert.EqualError(t, err, "include error in cycle.json -> cycle2.json: cycle.json is included cyclically")
+
+	err = MergeWithOptions(tree.NewNode(), strings.NewReader(files["misplaced.json"]), 1, options("misplaced.json"))
+	assert.EqualError(t, err, "include error in misplaced.json: $include must be the first key of the object")
+
+	// the cycle is found with the paths spelled differently
+	err = MergeWithOptions(tree.NewNode(), strings.NewReader(files["back.json"]), 1, options("./back.json"))
+	assert.EqualError(t, err, "include error in ./back.json -> cycle3.json: back.json is included cyclically")
+
+	err = MergeWithOptions(tree.NewNode(), strings.NewReader(files["nocomma.json"]), 1, options("nocomma.json"))
+	assert.EqualError(t, err, `unexpect token ("pool.json") at line 1, row 29`)
+}
+
+func TestNestedError(t *testing.T) {
+	// the errors in the values of nested objects are not dropped
+	cases := map[string]string{
+		`{"a": {"b": }}`:            "unexpect token ('}') at line 1, row 13",
+		`{"a": {"b": {"c": [1, }}}`: "unexpect token ('}') at line 1, row 23",
+	}
+	for json, expect := range cases {
+		err := Merge(tree.NewNode(), strings.NewReader(json), 1)
+		assert.EqualError(t, err, expect, json)
+	}
+}
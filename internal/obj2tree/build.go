@@ -1,22 +1,40 @@
 package obj2tree
 
 import (
+	"github.com/SnowPhoenix0105/cfgm/internal/fields"
 	"github.com/SnowPhoenix0105/cfgm/internal/tree"
 	"github.com/SnowPhoenix0105/deepcopy"
 	"reflect"
 )
 
+// Options of building, nil means the default options.
+type Options struct {
+	// Naming converts the names of fields to the keys, which is overridden by the name in the "cfgm"
+	// tag of a field, e.g. `cfgm:"pool_size"`.
+	Naming fields.Naming
+	// KeepPrototypes keeps the prototypes in the maps, which are removed from the maps after
+	// building by default, e.g. when the tree is built for dumping only.
+	KeepPrototypes bool
+}
+
 func BuildFrom(obj interface{}, time tree.ModifyTime) (*tree.Node, error) {
 	root := tree.NewNode()
-	err := AppendTo(obj, tree.WriteFrom(root, time))
+	err := AppendTo(obj, tree.WriteFrom(root, time), nil)
 	return root, err
 }
 
-func AppendTo(obj interface{}, walker tree.Walker) error {
+func AppendTo(obj interface{}, walker tree.Walker, options *Options) error {
+	if options == nil {
+		options = &Options{}
+	}
 	env := buildEnv{
-		Walker:       walker,
-		DescTag:      "desc",
-		PrototypeKey: "__prototype__",
+		Walker:         walker,
+		Naming:         options.Naming,
+		KeepPrototypes: options.KeepPrototypes,
+		DescTag:        "desc",
+		LevelTag:       "level",
+		SecretTag:      fields.SecretTag,
+		PrototypeKey:   "__prototype__",
 		DeepCopy: deepcopy.WithOptions(&deepcopy.Options{
 			IgnoreUnexploredFields: false,
 		}),
@@ -56,6 +56,20 @@ func TestBuildFrom_ObjPrototype(t *testing.T) {
 	assert.Equal(t, 0, len(obj))
 }
 
+func TestAppendTo_KeepPrototypes(t *testing.T) {
+	obj := map[string]int{
+		"__prototype__": 1,
+	}
+
+	actual := tree.NewNode()
+	err := AppendTo(obj, tree.WriteFrom(actual, 1), &Options{KeepPrototypes: true})
+
+	expect, _ := BuildFrom(map[string]int{"__prototype__": 1}, 1)
+	assert.Nil(t, err)
+	assert.True(t, tree.Equals(expect, actual))
+	assert.Equal(t, map[string]int{"__prototype__": 1}, obj)
+}
+
 func TestBuildFrom_ListPrototype(t *testing.T) {
 	obj := map[string][]int{
 		"A": []int{
@@ -1,15 +1,25 @@
 package obj2tree
 
 import (
+	"encoding"
+	"github.com/SnowPhoenix0105/cfgm/internal/check"
+	"github.com/SnowPhoenix0105/cfgm/internal/fields"
 	"github.com/SnowPhoenix0105/cfgm/internal/tree"
 	"github.com/SnowPhoenix0105/deepcopy"
+	"math"
+	"net/url"
 	"reflect"
+	"time"
 )
 
 type buildEnv struct {
 	DescTag             string
+	LevelTag            string
+	SecretTag           string
 	PrototypeKey        string
 	Walker              tree.Walker
+	Naming              fields.Naming
+	KeepPrototypes      bool
 	DeepCopy            deepcopy.Copier
 	prototypeKeyReflect reflect.Value
 }
@@ -24,9 +34,22 @@ func (env *buildEnv) buildFrom(obj reflect.Value, property kvProperty) error {
 }
 
 func (env *buildEnv) distribute(obj reflect.Value, property kvProperty) error {
+	switch obj.Type() {
+	case check.DurationType:
+		return env.buildFromDuration(obj, property)
+	case check.TimeType:
+		return env.buildFromTime(obj, property)
+	case check.URLType:
+		return env.buildFromURL(obj, property)
+	}
+	if obj.CanInterface() && check.IsTextType(obj.Type()) {
+		return env.buildFromText(obj, property)
+	}
 	switch obj.Kind() {
 	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
 		return env.buildFromInt(obj, property)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return env.buildFromUint(obj, property)
 	case reflect.Float32, reflect.Float64:
 		return env.buildFromFloat(obj, property)
 	case reflect.Bool:
@@ -37,8 +60,12 @@ func (env *buildEnv) distribute(obj reflect.Value, property kvProperty) error {
 		return env.buildFromStruct(obj, property)
 	case reflect.Slice:
 		return env.buildFromSlice(obj, property)
+	case reflect.Array:
+		return env.buildFromArray(obj, property)
 	case reflect.Map:
 		return env.buildFromMap(obj, property)
+	case reflect.Interface:
+		return env.buildFromInterface(obj, property)
 	}
 	// TODO error
 	panic("not implement")
@@ -53,6 +80,21 @@ func (env *buildEnv) buildFromInt(obj reflect.Value, property kvProperty) error
 	return nil
 }
 
+// buildFromUint builds an int as buildFromInt, and a uint only for the values above math.MaxInt64.
+func (env *buildEnv) buildFromUint(obj reflect.Value, property kvProperty) error {
+	value := obj.Uint()
+	if value <= math.MaxInt64 {
+		env.Walker.SetInt(int64(value))
+		env.Walker.SetNullFor(tree.NodeKeyInt, property.isNull)
+	} else {
+		env.Walker.SetUint(value)
+		env.Walker.SetNullFor(tree.NodeKeyUint, property.isNull)
+	}
+	env.Walker.SetNullableFor(tree.NodeKeyInt, property.nullable)
+	env.Walker.SetNullableFor(tree.NodeKeyUint, property.nullable)
+	return nil
+}
+
 func (env *buildEnv) buildFromFloat(obj reflect.Value, property kvProperty) error {
 	env.Walker.SetFloat(obj.Float())
 	env.Walker.SetNullFor(tree.NodeKeyFloat, property.isNull)
@@ -76,15 +118,86 @@ func (env *buildEnv) buildFromString(obj reflect.Value, property kvProperty) err
 
 // <<----- simple-type end ----->>
 
+// <<<==== time begin ====>>>
+
+// buildFromDuration builds a string like "1m30s", which is easier to write than the nanoseconds.
+func (env *buildEnv) buildFromDuration(obj reflect.Value, property kvProperty) error {
+	env.Walker.SetString(time.Duration(obj.Int()).String())
+	env.Walker.SetNullFor(tree.NodeKeyString, property.isNull)
+	env.Walker.SetNullableFor(tree.NodeKeyString, property.nullable)
+	return nil
+}
+
+// buildFromTime builds a string in RFC 3339, e.g. "2006-01-02T15:04:05Z".
+func (env *buildEnv) buildFromTime(obj reflect.Value, property kvProperty) error {
+	env.Walker.SetString(obj.Interface().(time.Time).Format(time.RFC3339Nano))
+	env.Walker.SetNullFor(tree.NodeKeyString, property.isNull)
+	env.Walker.SetNullableFor(tree.NodeKeyString, property.nullable)
+	return nil
+}
+
+// <<----- time end ----->>
+
+// <<<==== url begin ====>>>
+
+/*
+buildFromURL builds the string of the url, e.g. "https://example.com/path". url.URL does not
+implement encoding.TextMarshaler, so it is supported explicitly.
+*/
+func (env *buildEnv) buildFromURL(obj reflect.Value, property kvProperty) error {
+	u := obj.Interface().(url.URL)
+	env.Walker.SetString(u.String())
+	env.Walker.SetNullFor(tree.NodeKeyString, property.isNull)
+	env.Walker.SetNullableFor(tree.NodeKeyString, property.nullable)
+	return nil
+}
+
+// <<----- url end ----->>
+
+// <<<==== text begin ====>>>
+
+/*
+buildFromText builds a string by MarshalText, and an empty string for the types which only
+implement TextUnmarshaler.
+
+Errors:
+
+	MarshalTextError: MarshalText returns an error
+*/
+func (env *buildEnv) buildFromText(obj reflect.Value, property kvProperty) error {
+	if !obj.CanAddr() {
+		// the methods of pointer receiver need an addressable value, e.g. the prototypes
+		cpy := reflect.New(obj.Type()).Elem()
+		cpy.Set(obj)
+		obj = cpy
+	}
+	text := ""
+	if marshaler, ok := obj.Addr().Interface().(encoding.TextMarshaler); ok {
+		data, err := marshaler.MarshalText()
+		if err != nil {
+			return &MarshalTextError{Type: obj.Type().String(), Inner: err}
+		}
+		text = string(data)
+	}
+	env.Walker.SetString(text)
+	env.Walker.SetNullFor(tree.NodeKeyString, property.isNull)
+	env.Walker.SetNullableFor(tree.NodeKeyString, property.nullable)
+	return nil
+}
+
+// <<----- text end ----->>
+
 // <<<==== struct begin ====>>>
 
+// buildFromStruct builds the fields promoted from the embedded structs in obj too, see fields.Of().
 func (env *buildEnv) buildFromStruct(obj reflect.Value, property kvProperty) error {
-	typ := obj.Type()
-	numField := obj.NumField()
-	for i := 0; i < numField; i++ {
-		fieldType := typ.Field(i)
-		fieldValue := obj.Field(i)
-		err := env.buildFromField(fieldValue, fieldType)
+	for _, field := range fields.Of(obj.Type(), env.Naming) {
+		fieldValue, ok := fields.ByIndex(obj, field.Index, false)
+		if !ok {
+			// promoted from a nil embedded pointer
+			fieldValue = reflect.Zero(field.StructField.Type)
+		}
+		err := env.buildFromField(fieldValue, field.Name, field.StructField)
 		if err != nil {
 			return err
 		}
@@ -115,8 +228,8 @@ func (env *buildEnv) unwrapPointerForField(ptr reflect.Value) (elem reflect.Valu
 	return elem1, kvProperty{false, true}
 }
 
-func (env *buildEnv) buildFromField(obj reflect.Value, field reflect.StructField) error {
-	env.Walker.EnterObj(field.Name)
+func (env *buildEnv) buildFromField(obj reflect.Value, name string, field reflect.StructField) error {
+	env.Walker.EnterObj(name)
 	defer env.Walker.Exit()
 
 	elem, property := env.unwrapPointerForField(obj)
@@ -132,6 +245,18 @@ func (env *buildEnv) buildFromField(obj reflect.Value, field reflect.StructField
 	if len(desc) != 0 {
 		env.Walker.SetDesc(desc)
 	}
+
+	if levelTag, ok := field.Tag.Lookup(env.LevelTag); ok {
+		level, ok := tree.ParseLevel(levelTag)
+		if !ok {
+			return &LevelError{Field: field.Name, Level: levelTag}
+		}
+		env.Walker.SetLevel(level)
+	}
+
+	if field.Tag.Get(env.SecretTag) == "true" {
+		tree.MarkSecret(env.Walker)
+	}
 	return nil
 }
 
@@ -200,13 +325,63 @@ func (env *buildEnv) buildFromSlice(obj reflect.Value, property kvProperty) erro
 
 // <<----- slice end ----->>
 
+// <<<==== array begin ====>>>
+
+/*
+buildFromArray builds a list like buildFromSlice, and the prototype is always built from the zero
+value, since the length of an array cannot be used to provide one.
+*/
+func (env *buildEnv) buildFromArray(obj reflect.Value, property kvProperty) error {
+	isPtr := obj.Type().Elem().Kind() == reflect.Ptr
+	if isPtr && obj.Type().Elem().Elem().Kind() == reflect.Ptr {
+		return newPointerError(2, "elem type of array")
+	}
+	env.Walker.SetClearWhenEnterFor(tree.NodeKeyList, !isPtr)
+
+	env.Walker.EnterListPrototype()
+	typ := obj.Type().Elem()
+	if typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+	}
+	err := env.buildFrom(reflect.Zero(typ), kvProperty{false, false})
+	env.Walker.Exit()
+	if err != nil {
+		return err
+	}
+
+	length := obj.Len()
+	for i := 0; i < length; i++ {
+		env.Walker.EnterList(i)
+		elem := obj.Index(i)
+		err := env.buildFrom(env.unwrapPointerForElem(elem))
+		env.Walker.Exit()
+		if err != nil {
+			return err
+		}
+	}
+
+	env.Walker.SetNullFor(tree.NodeKeyList, property.isNull)
+	env.Walker.SetNullableFor(tree.NodeKeyList, property.nullable)
+	return nil
+}
+
+// <<----- array end ----->>
+
 // <<<==== map begin ====>>>
 
 func (env *buildEnv) buildFromKvPair(obj, key, value *reflect.Value) error {
 	elem, prop := env.unwrapPointerForElem(*value)
 	var err error
 	if !prop.nullable {
-		cpy := env.DeepCopy.AddressableOfReflect(elem)
+		var cpy reflect.Value
+		if elem.Kind() == reflect.Interface {
+			// deepcopy breaks checkptr on the interfaces, and buildFromInterface() copies the
+			// dynamic value anyway
+			cpy = reflect.New(elem.Type()).Elem()
+			cpy.Set(elem)
+		} else {
+			cpy = env.DeepCopy.AddressableOfReflect(elem)
+		}
 		err = env.buildFrom(cpy, prop)
 		obj.SetMapIndex(*key, cpy)
 	} else {
@@ -233,7 +408,9 @@ func (env *buildEnv) buildFromMap(obj reflect.Value, property kvProperty) error
 		if err != nil {
 			return err
 		}
-		obj.SetMapIndex(env.prototypeKeyReflect, reflect.Value{})
+		if !env.KeepPrototypes {
+			obj.SetMapIndex(env.prototypeKeyReflect, reflect.Value{})
+		}
 	} else {
 		env.Walker.EnterObjPrototype()
 		typ := obj.Type().Elem()
@@ -250,6 +427,9 @@ func (env *buildEnv) buildFromMap(obj reflect.Value, property kvProperty) error
 	iter := obj.MapRange()
 	for iter.Next() {
 		key := iter.Key()
+		if env.KeepPrototypes && key.String() == env.PrototypeKey {
+			continue
+		}
 		value := iter.Value()
 		env.Walker.EnterObj(key.String())
 		err := env.buildFromKvPair(&obj, &key, &value)
@@ -265,3 +445,26 @@ func (env *buildEnv) buildFromMap(obj reflect.Value, property kvProperty) error
 }
 
 // <<----- map end ----->>
+
+// <<<==== interface begin ====>>>
+
+/*
+buildFromInterface builds from the dynamic value of an interface, and nothing is built for a nil
+interface, so that any value can be merged into the node. The dynamic value is always nullable,
+since nil is a valid value of interface.
+*/
+func (env *buildEnv) buildFromInterface(obj reflect.Value, property kvProperty) error {
+	if obj.IsNil() {
+		return nil
+	}
+	// the dynamic value is copied to be addressable, e.g. the length of a slice may be changed
+	elem := reflect.New(obj.Elem().Type()).Elem()
+	elem.Set(obj.Elem())
+	if elem.Kind() == reflect.Ptr {
+		return newPointerError(1, "dynamic value of interface")
+	}
+	property.nullable = true
+	return env.distribute(elem, property)
+}
+
+// <<----- interface end ----->>
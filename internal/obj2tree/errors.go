@@ -17,3 +17,25 @@ func newPointerError(level int, typ string) *PointerError {
 func (err *PointerError) Error() string {
 	return fmt.Sprintf("%d-level pointer is not allowed for %s.", err.Level, err.Type)
 }
+
+type LevelError struct {
+	Field string
+	Level string
+}
+
+func (err *LevelError) Error() string {
+	return fmt.Sprintf("invalid level %q of field %s, expect required, common or advanced.", err.Level, err.Field)
+}
+
+type MarshalTextError struct {
+	Type  string
+	Inner error
+}
+
+func (err *MarshalTextError) Error() string {
+	return fmt.Sprintf("cannot marshal the default value of %s to text: %s", err.Type, err.Inner.Error())
+}
+
+func (err *MarshalTextError) Unwrap() error {
+	return err.Inner
+}
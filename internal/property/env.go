@@ -0,0 +1,114 @@
+package property
+
+import (
+	"errors"
+	"fmt"
+	"github.com/SnowPhoenix0105/cfgm/internal/tree"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+type EnvPropertyParseOptions struct {
+	Prefix    string
+	Separator string
+}
+
+func normalizeEnvName(name string) string {
+	name = strings.ReplaceAll(name, "_", "")
+	name = strings.ReplaceAll(name, "-", "")
+	return strings.ToLower(name)
+}
+
+func sortedObjKeys(walker tree.ReadonlyWalker) []string {
+	keys := walker.ObjKeys()
+	sort.Strings(keys)
+	return keys
+}
+
+/*
+resolveEnvPath finds the path in the tree for the parts of an environment variable name.
+Names are matched ignoring case, '_' and '-', so that "POOL_SIZE" matches "PoolSize" as well as
+"pool_size". A decimal part matches the index of a list, and an unknown part matches a new key
+of a map whose prototype is provided.
+*/
+func resolveEnvPath(walker tree.ReadonlyWalker, parts []string) ([]string, bool) {
+	if len(parts) == 0 {
+		return nil, true
+	}
+
+	if walker.Has(tree.NodeKeyList) {
+		index, err := strconv.ParseUint(parts[0], 10, 31)
+		if err == nil && walker.TryEnterList(int(index)) {
+			rest, ok := resolveEnvPath(walker, parts[1:])
+			walker.Exit()
+			if ok {
+				return append([]string{"[" + parts[0] + "]"}, rest...), true
+			}
+		}
+	}
+
+	keys := sortedObjKeys(walker)
+	for end := 1; end <= len(parts); end++ {
+		name := normalizeEnvName(strings.Join(parts[:end], ""))
+		for _, key := range keys {
+			if normalizeEnvName(key) != name || !walker.TryEnterObj(key) {
+				continue
+			}
+			rest, ok := resolveEnvPath(walker, parts[end:])
+			walker.Exit()
+			if ok {
+				return append([]string{key}, rest...), true
+			}
+		}
+	}
+
+	if walker.TryEnterObjPrototype() {
+		rest, ok := resolveEnvPath(walker, parts[1:])
+		walker.Exit()
+		if ok {
+			return append([]string{parts[0]}, rest...), true
+		}
+	}
+	return nil, false
+}
+
+/*
+ParseFromEnv builds a Record from environment variables like "APP_DB_POOL_SIZE=16", whose name
+starts with options.Prefix and options.Separator. The name is mapped onto the path of root, and the
+variables which cannot be mapped are ignored.
+
+Errors:
+
+	error: two environment variables are mapped to the same path
+*/
+func ParseFromEnv(environ []string, options *EnvPropertyParseOptions, root *tree.Node) (Record, error) {
+	record := Record{root: newNode()}
+	if len(options.Prefix) == 0 {
+		return record, nil
+	}
+	prefix := options.Prefix + options.Separator
+	for _, env := range environ {
+		index := strings.IndexByte(env, '=')
+		if index < 0 || !strings.HasPrefix(env[:index], prefix) {
+			continue
+		}
+		name := env[len(prefix):index]
+		if len(name) == 0 {
+			continue
+		}
+		path, ok := resolveEnvPath(tree.ReadFrom(root), strings.Split(name, options.Separator))
+		if !ok {
+			continue
+		}
+		ptr := record.root
+		for _, p := range path {
+			ptr = enterNode(ptr, p, "$"+env[:index])
+		}
+		if len(ptr.value) != 0 {
+			return Record{root: nil}, errors.New(fmt.Sprintf("environment variable conflict at %s", env[:index]))
+		}
+		ptr.value = env[index+1:]
+	}
+	return record, nil
+}
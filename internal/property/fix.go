@@ -3,6 +3,7 @@ package property
 import (
 	"github.com/SnowPhoenix0105/cfgm/internal/tree"
 	"strconv"
+	"strings"
 )
 
 type fixEnv struct {
@@ -16,15 +17,64 @@ func (env *fixEnv) getListLength() int {
 	return len(env.walker.List())
 }
 
+func (env *fixEnv) setString(value string) {
+	tree.KeepValues(env.walker, tree.NodeKeyString)
+	env.walker.SetString(value)
+}
+
+func (env *fixEnv) setInt(value int64) {
+	tree.KeepValues(env.walker, tree.NodeKeyInt, tree.NodeKeyFloat)
+	env.walker.SetInt(value)
+	env.walker.SetFloat(float64(value))
+}
+
+// setUint sets the integers above math.MaxInt64, which cannot be set by setInt.
+func (env *fixEnv) setUint(value uint64) {
+	tree.KeepValues(env.walker, tree.NodeKeyUint, tree.NodeKeyFloat)
+	env.walker.SetUint(value)
+	env.walker.SetFloat(float64(value))
+}
+
+func (env *fixEnv) setFloat(value float64) {
+	tree.KeepValues(env.walker, tree.NodeKeyFloat)
+	env.walker.SetFloat(value)
+}
+
+func (env *fixEnv) setBool(value bool) {
+	tree.KeepValues(env.walker, tree.NodeKeyBool)
+	env.walker.SetBool(value)
+}
+
+// isStringNode reports whether the current node only holds a string, e.g. built from a string field.
+func (env *fixEnv) isStringNode() bool {
+	return env.walker.Has(tree.NodeKeyString) &&
+		!env.walker.Has(tree.NodeKeyInt) &&
+		!env.walker.Has(tree.NodeKeyUint) &&
+		!env.walker.Has(tree.NodeKeyFloat) &&
+		!env.walker.Has(tree.NodeKeyBool)
+}
+
+/*
+assign guesses the type of value, e.g. "16" is an int and "true" is a bool, but the value is always
+a string for a node which only holds a string, so that "-Dname=123" works for a string field.
+*/
 func (env *fixEnv) assign(value string) {
 	length := len(value)
 	if length == 0 {
 		if env.walker.Has(tree.NodeKeyBool) {
-			env.walker.SetBool(true)
+			env.setBool(true)
 		}
 		return
 	}
 	prefix := value[0]
+	if prefix == '"' && length > 1 && value[length-1] == '"' {
+		env.setString(value[1 : length-1])
+		return
+	}
+	if env.isStringNode() {
+		env.setString(value)
+		return
+	}
 	if prefix == '0' && len(value) > 2 {
 		base := 8
 		beg := 1
@@ -36,37 +86,43 @@ func (env *fixEnv) assign(value string) {
 			base = 2
 			beg = 2
 		}
-		i, err := strconv.ParseInt(value[beg:length-1], base, 64)
+		i, err := strconv.ParseInt(value[beg:], base, 64)
+		if err == nil {
+			env.setInt(i)
+			return
+		}
+		u, err := strconv.ParseUint(value[beg:], base, 64)
 		if err == nil {
-			env.walker.SetInt(i)
+			env.setUint(u)
 			return
 		}
 	}
-	if '0' <= prefix && prefix <= '9' {
+	if ('0' <= prefix && prefix <= '9') || prefix == '-' || prefix == '+' {
 		i, err := strconv.ParseInt(value, 10, 64)
 		if err == nil {
-			env.walker.SetInt(i)
+			env.setInt(i)
+			return
+		}
+		u, err := strconv.ParseUint(value, 10, 64)
+		if err == nil {
+			env.setUint(u)
 			return
 		}
 		f, err := strconv.ParseFloat(value, 64)
 		if err == nil {
-			env.walker.SetFloat(f)
+			env.setFloat(f)
 			return
 		}
 	}
-	if prefix == '"' && value[length-1] == '"' {
-		env.walker.SetString(value[1 : length-1])
-		return
-	}
 	if value == "true" {
-		env.walker.SetBool(true)
+		env.setBool(true)
 		return
 	}
 	if value == "false" {
-		env.walker.SetBool(false)
+		env.setBool(false)
 		return
 	}
-	env.walker.SetString(value)
+	env.setString(value)
 }
 
 func listIndex(base int, name string) int {
@@ -84,19 +140,39 @@ func listIndex(base int, name string) int {
 	return -1
 }
 
-func (env *fixEnv) fixNode(ptr *node) {
+/*
+fixNode fixes the current node of walker with ptr, and reports whether any secret node is fixed.
+The value in the source of a secret node or its ancestors is redacted, e.g. "-Ddb.Password=******".
+*/
+func (env *fixEnv) fixNode(ptr *node) bool {
+	if len(ptr.source) != 0 {
+		env.walker.SetSource(ptr.source)
+	}
 	env.assign(ptr.value)
+	secret := env.walker.Secret()
 	baseLength := env.getListLength()
 	for k, v := range ptr.sub {
 		index := listIndex(baseLength, k)
 		if index >= 0 {
 			env.walker.EnterList(index)
-			env.fixNode(v)
+			secret = env.fixNode(v) || secret
 			env.walker.Exit()
 			continue
 		}
 		env.walker.EnterObj(k)
-		env.fixNode(v)
+		secret = env.fixNode(v) || secret
 		env.walker.Exit()
 	}
+	if secret && len(ptr.source) != 0 {
+		env.walker.SetSource(redactSource(ptr.source))
+	}
+	return secret
+}
+
+func redactSource(source string) string {
+	index := strings.IndexByte(source, '=')
+	if index < 0 {
+		return source
+	}
+	return source[:index+1] + tree.Redacted
 }
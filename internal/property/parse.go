@@ -12,42 +12,49 @@ type CmdPropertyParseOptions struct {
 	PropertyPrefix   string
 }
 
-func ParseFromCmd(cmdLines []string, options *CmdPropertyParseOptions) (string, Record, error) {
+/*
+ParseFromCmd finds the config file paths and the properties from cmdLines. All the config file
+paths are returned in order, each of them may be a file or a directory.
+*/
+func ParseFromCmd(cmdLines []string, options *CmdPropertyParseOptions) ([]string, Record, error) {
 	propList := make([]string, 0)
-	configFilePath := ""
+	sources := make([]string, 0)
+	configFilePaths := make([]string, 0)
 	for _, cmd := range cmdLines {
 		if strings.HasPrefix(cmd, options.ConfigFilePrefix) {
-			configFilePath = strings.TrimPrefix(cmd, options.ConfigFilePrefix)
+			configFilePaths = append(configFilePaths, strings.TrimPrefix(cmd, options.ConfigFilePrefix))
 		} else if strings.HasPrefix(cmd, options.PropertyPrefix) {
 			propList = append(propList, strings.TrimPrefix(cmd, options.PropertyPrefix))
+			sources = append(sources, cmd)
 		}
 	}
-	record, err := ParseFromPropertyList(propList)
-	return configFilePath, record, err
+	record, err := parsePropertyList(propList, sources)
+	return configFilePaths, record, err
 }
 
-func enterNode(ptr *node, path string) *node {
+func enterNode(ptr *node, path string, source string) *node {
 	next, ok := ptr.sub[path]
 	if !ok {
 		next = newNode()
+		next.source = source
 		ptr.sub[path] = next
 	}
 	return next
 }
 
-func appendProperty(root *node, prop string) error {
+func appendProperty(root *node, prop string, source string) error {
 	beg := 0
 	ptr := root
 	length := len(prop)
 	for end := 1; end < length; {
 		if prop[end] == '.' {
-			ptr = enterNode(ptr, prop[beg:end])
+			ptr = enterNode(ptr, prop[beg:end], source)
 			beg = end + 1
 			end += 2
 			continue
 		}
 		if prop[end] == '=' {
-			ptr = enterNode(ptr, prop[beg:end])
+			ptr = enterNode(ptr, prop[beg:end], source)
 			if len(ptr.value) != 0 {
 				// this no has been set by another property
 				return errors.New(fmt.Sprintf("property conflict at %s", prop[0:end]))
@@ -64,15 +71,19 @@ func appendProperty(root *node, prop string) error {
 	if beg == length {
 		return errors.New(fmt.Sprintf("invalid property: %s", prop))
 	}
-	ptr = enterNode(ptr, prop[beg:length])
+	ptr = enterNode(ptr, prop[beg:length], source)
 	// set this node as an empty node
 	return nil
 }
 
 func ParseFromPropertyList(propList []string) (Record, error) {
+	return parsePropertyList(propList, propList)
+}
+
+func parsePropertyList(propList []string, sources []string) (Record, error) {
 	root := newNode()
-	for _, prop := range propList {
-		err := appendProperty(root, prop)
+	for i, prop := range propList {
+		err := appendProperty(root, prop, sources[i])
 		if err != nil {
 			return Record{root: nil}, err
 		}
@@ -7,6 +7,9 @@ type Record struct {
 type node struct {
 	value string
 	sub   map[string]*node
+
+	// source is the property or the environment variable which creates this node
+	source string
 }
 
 func newNode() *node {
@@ -0,0 +1,26 @@
+package resolve
+
+import "fmt"
+
+/*
+ReferenceError describes a reference which cannot be resolved, Source is where the string comes
+from, e.g. "config.json:3:5", and it is empty for the default value.
+*/
+type ReferenceError struct {
+	Path      string
+	Reference string
+	Source    string
+	Inner     error
+}
+
+func (err *ReferenceError) Error() string {
+	source := err.Source
+	if len(source) == 0 {
+		source = "default"
+	}
+	return fmt.Sprintf("cfgm resolve error: cannot resolve %s at (%s) from %s: %s", err.Reference, err.Path, source, err.Inner.Error())
+}
+
+func (err *ReferenceError) Unwrap() error {
+	return err.Inner
+}
@@ -0,0 +1,403 @@
+package resolve
+
+import (
+	"errors"
+	"fmt"
+	"github.com/SnowPhoenix0105/cfgm/internal/tree"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	schemeFile = "file"
+	schemeEnv  = "env"
+)
+
+type Options struct {
+	// Environ returns the environment variables as "key=value".
+	Environ func() []string
+
+	// ReadFile reads the file of a "${file:path}" reference.
+	ReadFile func(name string) ([]byte, error)
+
+	// Time is set as the modify time of the nodes changed by the references and their ancestors,
+	// so that the values resolved from the defaults are not taken as the defaults, see
+	// tree2obj.RefillFrom. The modify times are kept if it is zero.
+	Time tree.ModifyTime
+}
+
+type nodeState int
+
+const (
+	nodeStateUnresolved nodeState = iota
+	nodeStateResolving
+	nodeStateResolved
+)
+
+type resolveEnv struct {
+	options *Options
+	root    *tree.Node
+	environ map[string]string
+	path    []string
+	states  map[*tree.Node]nodeState
+	// changed is the nodes changed by the references, true means the whole subtree is copied
+	changed map[*tree.Node]bool
+	// chain is the paths of the strings whose references are being resolved, for the cycle errors
+	chain []string
+	// secretReferred is set when a secret node is referred, so the referring node is secret too
+	secretReferred bool
+}
+
+/*
+Resolve replaces the references in the strings of the tree with the values they refer to, so that
+the secrets can be kept out of the config files, and the repeated values can be written once.
+A string may contain several references, and "$${" is an escaped "${".
+
+Supported:
+
+	${file:path}: the content of the file, without the trailing line break
+	${env:NAME}: the value of the environment variable
+	${a.b.[0]}: the value at the path of the tree, which is resolved before being referred
+
+A reference without a scheme is kept as it is when there is no value at its path, so the strings
+like "${HOME}/data" in the shell snippets or the templates of logs are not broken.
+
+A string which is exactly a reference to a path, e.g. "${db.primary}", is replaced by a copy of
+the node at the path, so an object or a list can be referred in this way. The scalars referred
+in this way keep their types, and they can still be refilled to the string fields.
+
+Errors:
+
+	ReferenceError: the reference cannot be resolved, e.g. the file does not exist, the path is
+	not found, or the references form a cycle
+*/
+func Resolve(root *tree.Node, options *Options) error {
+	env := resolveEnv{
+		options: options,
+		root:    root,
+		path:    make([]string, 0),
+		states:  make(map[*tree.Node]nodeState),
+		changed: make(map[*tree.Node]bool),
+		chain:   make([]string, 0),
+	}
+	err := env.resolve(root)
+	if err != nil {
+		return err
+	}
+	if options.Time != 0 {
+		env.touch(root, false)
+	}
+	return nil
+}
+
+/*
+touch sets the modify time of the changed nodes and their ancestors, and all the nodes in the
+copied subtrees, then reports whether any node in the subtree of node is changed.
+*/
+func (env *resolveEnv) touch(node *tree.Node, copied bool) bool {
+	whole, changed := env.changed[node]
+	changed = changed || copied
+	copied = copied || whole
+	if node.Has(tree.NodeKeyObj) {
+		for _, next := range node.Obj() {
+			changed = env.touch(next, copied) || changed
+		}
+	}
+	if node.Has(tree.NodeKeyList) {
+		for _, next := range node.List() {
+			changed = env.touch(next, copied) || changed
+		}
+	}
+	if changed {
+		node.SetModifyTime(env.options.Time)
+	}
+	return changed
+}
+
+func (env *resolveEnv) resolve(node *tree.Node) error {
+	switch env.states[node] {
+	case nodeStateResolved:
+		return nil
+	case nodeStateResolving:
+		// an ancestor refers to its descendant, which is resolved later
+		return nil
+	}
+	env.states[node] = nodeStateResolving
+	defer func() { env.states[node] = nodeStateResolved }()
+
+	if node.Has(tree.NodeKeyString) && !node.IsNullFor(tree.NodeKeyString) {
+		err := env.resolveNodeString(node)
+		if err != nil {
+			return err
+		}
+	}
+	if node.Has(tree.NodeKeyObj) {
+		obj := node.Obj()
+		// the keys are sorted to make the first error stable
+		keys := make([]string, 0, len(obj))
+		for key := range obj {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			env.path = append(env.path, key)
+			err := env.resolve(obj[key])
+			env.path = env.path[:len(env.path)-1]
+			if err != nil {
+				return err
+			}
+		}
+	}
+	if node.Has(tree.NodeKeyList) {
+		for i, next := range node.List() {
+			env.path = append(env.path, "["+strconv.Itoa(i)+"]")
+			err := env.resolve(next)
+			env.path = env.path[:len(env.path)-1]
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (env *resolveEnv) resolveNodeString(node *tree.Node) error {
+	str := node.String()
+	if !strings.Contains(str, "${") {
+		return nil
+	}
+	env.chain = append(env.chain, strings.Join(env.path, "."))
+	defer func() { env.chain = env.chain[:len(env.chain)-1] }()
+
+	newReferenceError := func(reference string, err error) error {
+		var refErr *ReferenceError
+		if errors.As(err, &refErr) {
+			// the error of the referred node
+			return err
+		}
+		return &ReferenceError{
+			Path:      strings.Join(env.path, "."),
+			Reference: reference,
+			Source:    node.Source(),
+			Inner:     err,
+		}
+	}
+
+	if content, ok := wholeReference(str); ok && isPathReference(content) {
+		target, err := env.resolvePath(content)
+		if err != nil {
+			return newReferenceError(str, err)
+		}
+		if target == nil {
+			return nil
+		}
+		tree.CopyValues(node, target)
+		env.changed[node] = true
+		if target.Secret() {
+			node.SetSecret(true)
+		}
+		// the copied nodes have been resolved, e.g. "$${" has been unescaped
+		env.markResolved(node)
+		if !node.Has(tree.NodeKeyString) {
+			if value, ok := formatScalar(target); ok {
+				node.SetString(value)
+			}
+		}
+		return nil
+	}
+
+	referred := env.secretReferred
+	env.secretReferred = false
+	value, reference, err := env.resolveString(str)
+	if env.secretReferred {
+		node.SetSecret(true)
+	}
+	env.secretReferred = referred
+	if err != nil {
+		return newReferenceError(reference, err)
+	}
+	if value != str {
+		node.SetString(value)
+		env.changed[node] = false
+	}
+	return nil
+}
+
+func (env *resolveEnv) markResolved(node *tree.Node) {
+	if node.Has(tree.NodeKeyObj) {
+		for _, next := range node.Obj() {
+			env.states[next] = nodeStateResolved
+			env.markResolved(next)
+		}
+	}
+	if node.Has(tree.NodeKeyList) {
+		for _, next := range node.List() {
+			env.states[next] = nodeStateResolved
+			env.markResolved(next)
+		}
+	}
+}
+
+// wholeReference reports whether str is exactly a reference, and returns the content of it.
+func wholeReference(str string) (string, bool) {
+	if !strings.HasPrefix(str, "${") || !strings.HasSuffix(str, "}") {
+		return "", false
+	}
+	content := str[2 : len(str)-1]
+	if strings.ContainsAny(content, "{}") {
+		return "", false
+	}
+	return content, true
+}
+
+func isPathReference(content string) bool {
+	return !strings.ContainsRune(content, ':')
+}
+
+// resolveString resolves the references in str, and returns the failed reference with the error.
+func (env *resolveEnv) resolveString(str string) (string, string, error) {
+	builder := strings.Builder{}
+	for len(str) != 0 {
+		index := strings.Index(str, "${")
+		if index < 0 {
+			builder.WriteString(str)
+			break
+		}
+		if index > 0 && str[index-1] == '$' {
+			// "$${" is escaped
+			builder.WriteString(str[:index-1])
+			builder.WriteString("${")
+			str = str[index+2:]
+			continue
+		}
+		builder.WriteString(str[:index])
+		str = str[index:]
+		end := strings.IndexByte(str, '}')
+		if end < 0 {
+			return "", str, errors.New("missing '}'")
+		}
+		reference := str[:end+1]
+		str = str[end+1:]
+		value, err := env.resolveReference(reference[2:end])
+		if err != nil {
+			return "", reference, err
+		}
+		builder.WriteString(value)
+	}
+	return builder.String(), "", nil
+}
+
+/*
+resolveReference resolves the content of a reference like "env:NAME" or "server.host", the
+references of unknown schemes are kept as they are.
+*/
+func (env *resolveEnv) resolveReference(content string) (string, error) {
+	if isPathReference(content) {
+		target, err := env.resolvePath(content)
+		if err != nil {
+			return "", err
+		}
+		if target == nil {
+			return "${" + content + "}", nil
+		}
+		value, ok := formatScalar(target)
+		if !ok {
+			return "", errors.New("an object or a list cannot be a part of string")
+		}
+		env.secretReferred = env.secretReferred || target.Secret()
+		return value, nil
+	}
+	index := strings.IndexByte(content, ':')
+	scheme, name := content[:index], content[index+1:]
+	switch scheme {
+	case schemeFile:
+		data, err := env.options.ReadFile(name)
+		if err != nil {
+			return "", err
+		}
+		value := strings.TrimSuffix(string(data), "\n")
+		return strings.TrimSuffix(value, "\r"), nil
+	case schemeEnv:
+		value, ok := env.getenv(name)
+		if !ok {
+			return "", fmt.Errorf("environment variable %s is not set", name)
+		}
+		return value, nil
+	}
+	return "${" + content + "}", nil
+}
+
+/*
+resolvePath finds the node at path and resolves it, a part like "[0]" means the index of a list.
+The node is nil without any error if not found.
+*/
+func (env *resolveEnv) resolvePath(path string) (*tree.Node, error) {
+	parts := strings.Split(path, ".")
+	node := env.root
+	for _, part := range parts {
+		node = child(node, part)
+		if node == nil {
+			return nil, nil
+		}
+	}
+	if env.states[node] == nodeStateResolving {
+		chain := append(append([]string{}, env.chain...), path)
+		return nil, fmt.Errorf("reference cycle: %s", strings.Join(chain, " -> "))
+	}
+
+	current := env.path
+	env.path = parts
+	err := env.resolve(node)
+	env.path = current
+	if err != nil {
+		return nil, err
+	}
+	return node, nil
+}
+
+func child(node *tree.Node, part string) *tree.Node {
+	if len(part) > 2 && part[0] == '[' && part[len(part)-1] == ']' {
+		index, err := strconv.ParseUint(part[1:len(part)-1], 10, 31)
+		if err == nil {
+			if !node.Has(tree.NodeKeyList) || int(index) >= len(node.List()) {
+				return nil
+			}
+			return node.List()[index]
+		}
+	}
+	if !node.Has(tree.NodeKeyObj) {
+		return nil
+	}
+	return node.Obj()[part]
+}
+
+// formatScalar formats the scalar value of node, false will be returned for an object or a list.
+func formatScalar(node *tree.Node) (string, bool) {
+	switch {
+	case node.Has(tree.NodeKeyString):
+		return node.String(), true
+	case node.Has(tree.NodeKeyInt):
+		return strconv.FormatInt(node.Int(), 10), true
+	case node.Has(tree.NodeKeyUint):
+		return strconv.FormatUint(node.Uint(), 10), true
+	case node.Has(tree.NodeKeyFloat):
+		return strconv.FormatFloat(node.Float(), 'g', -1, 64), true
+	case node.Has(tree.NodeKeyBool):
+		return strconv.FormatBool(node.Bool()), true
+	}
+	return "", false
+}
+
+func (env *resolveEnv) getenv(name string) (string, bool) {
+	if env.environ == nil {
+		env.environ = make(map[string]string)
+		for _, kv := range env.options.Environ() {
+			if index := strings.IndexByte(kv, '='); index >= 0 {
+				env.environ[kv[:index]] = kv[index+1:]
+			}
+		}
+	}
+	value, ok := env.environ[name]
+	return value, ok
+}
@@ -0,0 +1,139 @@
+package resolve
+
+import (
+	"errors"
+	"github.com/SnowPhoenix0105/cfgm/internal/json2tree"
+	"github.com/SnowPhoenix0105/cfgm/internal/tree"
+	"github.com/stretchr/testify/assert"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestResolve(t *testing.T) {
+	options := &Options{
+		Environ: func() []string {
+			return []string{"DB_USER=admin", "EMPTY="}
+		},
+		ReadFile: func(name string) ([]byte, error) {
+			if name == "/run/secrets/db_password" {
+				return []byte("p@ss\n"), nil
+			}
+			return nil, os.ErrNotExist
+		},
+	}
+	load := func(json string) *tree.Node {
+		root := tree.NewNode()
+		err := json2tree.MergeWithFileName(root, strings.NewReader(json), 1, "config.json")
+		assert.Nil(t, err)
+		return root
+	}
+
+	root := load(`{
+	"DB": {
+		"Password": "${file:/run/secrets/db_password}",
+		"URL": "postgres://${env:DB_USER}:${file:/run/secrets/db_password}@localhost${env:EMPTY}"
+	},
+	"Hosts": ["${env:DB_USER}", "$${env:DB_USER}", "$${HOME}"],
+	"Port": 5432
+}`)
+	assert.Nil(t, Resolve(root, options))
+	db := root.Obj()["DB"].Obj()
+	assert.Equal(t, "p@ss", db["Password"].String())
+	assert.Equal(t, "postgres://admin:p@ss@localhost", db["URL"].String())
+	hosts := root.Obj()["Hosts"].List()
+	assert.Equal(t, "admin", hosts[0].String())
+	assert.Equal(t, "${env:DB_USER}", hosts[1].String())
+	assert.Equal(t, "${HOME}", hosts[2].String())
+	assert.Equal(t, tree.ModifyTime(1), db["Password"].ModifyTime())
+
+	// the changed nodes and their ancestors are marked with the time
+	root = load(`{"DB": {"Password": "${env:DB_USER}", "Port": 5432}, "Name": "db"}`)
+	assert.Nil(t, Resolve(root, &Options{Environ: options.Environ, ReadFile: options.ReadFile, Time: 9}))
+	assert.Equal(t, tree.ModifyTime(9), root.ModifyTime())
+	assert.Equal(t, tree.ModifyTime(9), root.Obj()["DB"].ModifyTime())
+	assert.Equal(t, tree.ModifyTime(9), root.Obj()["DB"].Obj()["Password"].ModifyTime())
+	assert.Equal(t, tree.ModifyTime(1), root.Obj()["DB"].Obj()["Port"].ModifyTime())
+	assert.Equal(t, tree.ModifyTime(1), root.Obj()["Name"].ModifyTime())
+
+	root = load(`{
+	"DB": {
+		"Password": "${file:/run/secrets/missing}"
+	}
+}`)
+	err := Resolve(root, options)
+	refErr, ok := err.(*ReferenceError)
+	assert.True(t, ok)
+	assert.Equal(t, "DB.Password", refErr.Path)
+	assert.Equal(t, "${file:/run/secrets/missing}", refErr.Reference)
+	assert.Equal(t, "config.json:3:3", refErr.Source)
+	assert.True(t, errors.Is(err, os.ErrNotExist))
+
+	root = load(`{"Password": "${env:DB_PASSWORD}"}`)
+	err = Resolve(root, options)
+	assert.EqualError(t, err, "cfgm resolve error: cannot resolve ${env:DB_PASSWORD} at (Password) from config.json:1:2: environment variable DB_PASSWORD is not set")
+}
+
+func TestResolve_PathReferences(t *testing.T) {
+	options := &Options{
+		Environ:  func() []string { return []string{"HOST=example.com"} },
+		ReadFile: func(name string) ([]byte, error) { return nil, os.ErrNotExist },
+	}
+	load := func(json string) *tree.Node {
+		root := tree.NewNode()
+		err := json2tree.MergeWithFileName(root, strings.NewReader(json), 1, "config.json")
+		assert.Nil(t, err)
+		return root
+	}
+
+	root := load(`{
+	"Addr": "${Server.Host}:${Server.Port}",
+	"Backup": "${Server}",
+	"Port": "${Server.Port}",
+	"Server": {
+		"Host": "${Hosts.[0]}",
+		"Port": 8080,
+		"Home": "$${HOME}"
+	},
+	"Hosts": ["${env:HOST}"]
+}`)
+	assert.Nil(t, Resolve(root, options))
+	obj := root.Obj()
+	assert.Equal(t, "example.com:8080", obj["Addr"].String())
+	assert.Equal(t, int64(8080), obj["Port"].Int())
+	assert.Equal(t, "8080", obj["Port"].String())
+	backup := obj["Backup"]
+	assert.False(t, backup.Has(tree.NodeKeyString))
+	assert.Equal(t, "example.com", backup.Obj()["Host"].String())
+	assert.Equal(t, "${HOME}", backup.Obj()["Home"].String())
+	assert.Equal(t, "${HOME}", obj["Server"].Obj()["Home"].String())
+
+	root = load(`{
+	"A": "${B}",
+	"B": "x${C}",
+	"C": "${A}"
+}`)
+	err := Resolve(root, options)
+	assert.EqualError(t, err, "cfgm resolve error: cannot resolve ${A} at (C) from config.json:4:2: reference cycle: A -> B -> C -> A")
+
+	root = load(`{
+	"Server": {"Host": "localhost"},
+	"Name": "${Server}-1"
+}`)
+	err = Resolve(root, options)
+	assert.EqualError(t, err, "cfgm resolve error: cannot resolve ${Server} at (Name) from config.json:3:2: an object or a list cannot be a part of string")
+
+	root = load(`{"Server": {"Self": "${Server}"}}`)
+	err = Resolve(root, options)
+	assert.EqualError(t, err, "cfgm resolve error: cannot resolve ${Server} at (Server.Self) from config.json:1:13: reference cycle: Server.Self -> Server")
+
+	// the references to the paths not found are kept as they are
+	root = load(`{"Name": "${Unknown.Key}", "Home": "${HOME}", "Data": "${HOME}/data", "Log": "[${level}] ${Name}"}`)
+	assert.Nil(t, Resolve(root, &Options{Environ: options.Environ, ReadFile: options.ReadFile, Time: 9}))
+	obj = root.Obj()
+	assert.Equal(t, "${Unknown.Key}", obj["Name"].String())
+	assert.Equal(t, "${HOME}", obj["Home"].String())
+	assert.Equal(t, "${HOME}/data", obj["Data"].String())
+	assert.Equal(t, "[${level}] ${Unknown.Key}", obj["Log"].String())
+	assert.Equal(t, tree.ModifyTime(1), obj["Home"].ModifyTime())
+}
@@ -0,0 +1,31 @@
+package toml2tree
+
+import "fmt"
+
+type IOError struct {
+	Inner error
+}
+
+func (err *IOError) Error() string {
+	return fmt.Sprintf("unexpect IO error, may be caused by: %s", err.Inner.Error())
+}
+
+type SyntaxError struct {
+	Line    int
+	Row     int
+	Message string
+}
+
+func (err *SyntaxError) Error() string {
+	return fmt.Sprintf("syntax error at line %d, row %d: %s", err.Line, err.Row, err.Message)
+}
+
+type DuplicateKeyError struct {
+	Line int
+	Row  int
+	Key  string
+}
+
+func (err *DuplicateKeyError) Error() string {
+	return fmt.Sprintf("duplicate key (%s) at line %d, row %d", err.Key, err.Line, err.Row)
+}
@@ -0,0 +1,126 @@
+package toml2tree
+
+import (
+	"github.com/SnowPhoenix0105/cfgm/internal/tree"
+	"io"
+	"strings"
+)
+
+func MergeString(root *tree.Node, toml string, time tree.ModifyTime) error {
+	reader := strings.NewReader(toml)
+	return Merge(root, reader, time)
+}
+
+/*
+Merge parses the TOML document from reader, and merges it into root with the same rules of
+json2tree.Merge. Date-times are merged as strings.
+
+Errors:
+
+	IOError: fail to read from reader
+	SyntaxError: the document is not a valid TOML document
+	DuplicateKeyError: a key or a table is defined twice
+*/
+func Merge(root *tree.Node, reader io.RuneReader, time tree.ModifyTime) error {
+	return MergeWithFileName(root, reader, time, "")
+}
+
+/*
+MergeWithFileName works like Merge, and records the position of each key and item in the file
+as the source of its node, e.g. "config.toml:3:5".
+*/
+func MergeWithFileName(root *tree.Node, reader io.RuneReader, time tree.ModifyTime, fileName string) error {
+	src, err := readAll(reader)
+	if err != nil {
+		return err
+	}
+	par := newParser(src)
+	doc, err := par.parseDocument()
+	if err != nil {
+		return err
+	}
+	env := mergeEnv{
+		walker:   tree.WriteFrom(root, time),
+		fileName: fileName,
+	}
+	env.mergeTable(doc)
+	return nil
+}
+
+func readAll(reader io.RuneReader) ([]rune, error) {
+	src := make([]rune, 0)
+	for {
+		char, _, err := reader.ReadRune()
+		if err == io.EOF {
+			return src, nil
+		}
+		if err != nil {
+			return nil, &IOError{Inner: err}
+		}
+		// "\r\n" is normalized to "\n"
+		if char == '\n' && len(src) != 0 && src[len(src)-1] == '\r' {
+			src[len(src)-1] = '\n'
+			continue
+		}
+		src = append(src, char)
+	}
+}
+
+type mergeEnv struct {
+	walker   tree.Walker
+	fileName string
+}
+
+func (env *mergeEnv) setSource(value *tomlValue) {
+	env.walker.SetSource(tree.FilePosition(env.fileName, value.line, value.row))
+}
+
+func (env *mergeEnv) merge(value *tomlValue) {
+	switch value.kind {
+	case kindTable:
+		tree.KeepValues(env.walker, tree.NodeKeyObj)
+		env.mergeTable(value.table)
+	case kindArray:
+		tree.KeepValues(env.walker, tree.NodeKeyList)
+		for i, item := range value.items {
+			env.walker.EnterList(i)
+			env.setSource(item)
+			env.merge(item)
+			env.walker.Exit()
+		}
+	default:
+		env.mergeScalar(value.scalar)
+	}
+}
+
+func (env *mergeEnv) mergeTable(table *tomlTable) {
+	for _, key := range table.keys {
+		env.walker.EnterObj(key)
+		env.setSource(table.values[key])
+		env.merge(table.values[key])
+		env.walker.Exit()
+	}
+}
+
+func (env *mergeEnv) mergeScalar(scalar interface{}) {
+	switch value := scalar.(type) {
+	case int64:
+		tree.KeepValues(env.walker, tree.NodeKeyInt, tree.NodeKeyFloat)
+		env.walker.SetInt(value)
+		env.walker.SetNullFor(tree.NodeKeyInt, false)
+		env.walker.SetFloat(float64(value))
+		env.walker.SetNullFor(tree.NodeKeyFloat, false)
+	case float64:
+		tree.KeepValues(env.walker, tree.NodeKeyFloat)
+		env.walker.SetFloat(value)
+		env.walker.SetNullFor(tree.NodeKeyFloat, false)
+	case bool:
+		tree.KeepValues(env.walker, tree.NodeKeyBool)
+		env.walker.SetBool(value)
+		env.walker.SetNullFor(tree.NodeKeyBool, false)
+	case string:
+		tree.KeepValues(env.walker, tree.NodeKeyString)
+		env.walker.SetString(value)
+		env.walker.SetNullFor(tree.NodeKeyString, false)
+	}
+}
@@ -0,0 +1,199 @@
+package toml2tree
+
+import (
+	"github.com/SnowPhoenix0105/cfgm/internal/obj2tree"
+	"github.com/SnowPhoenix0105/cfgm/internal/tree"
+	"github.com/SnowPhoenix0105/cfgm/internal/tree2obj"
+	"github.com/stretchr/testify/assert"
+	"strconv"
+	"testing"
+)
+
+func enter(t *testing.T, root *tree.Node, path ...string) tree.ReadonlyWalker {
+	walker := tree.ReadFrom(root)
+	for _, p := range path {
+		if index, err := strconv.Atoi(p); err == nil {
+			assert.True(t, walker.TryEnterList(index), p)
+		} else {
+			assert.True(t, walker.TryEnterObj(p), p)
+		}
+	}
+	return walker
+}
+
+func TestMerge_Values(t *testing.T) {
+	toml := `
+# comment
+title = "TOML \"Example\"\u0041" # trailing comment
+literal = 'C:\Users\nodejs'
+multi = """
+Roses are red,\
+   Violets are blue"""
+raw = '''
+line1
+line2'''
+int = 1_000
+hex = 0xDEAD_beef
+oct = 0o755
+bin = 0b1101
+neg = -17
+float = 6.626e-34
+inf = -inf
+bool = true
+odt = 1979-05-27T07:32:00-08:00
+ldt = 1979-05-27 07:32:00.999
+ld = 1979-05-27
+lt = 07:32:00
+array = [
+	1,
+	2, # comment
+	[3, "four"],
+]
+"quoted key" = 1
+site."google.com" = true
+`
+	root := tree.NewNode()
+	err := MergeString(root, toml, 1)
+	assert.Nil(t, err)
+
+	assert.Equal(t, `TOML "Example"A`, enter(t, root, "title").String())
+	assert.Equal(t, `C:\Users\nodejs`, enter(t, root, "literal").String())
+	assert.Equal(t, "Roses are red,Violets are blue", enter(t, root, "multi").String())
+	assert.Equal(t, "line1\nline2", enter(t, root, "raw").String())
+	assert.Equal(t, int64(1000), enter(t, root, "int").Int())
+	assert.Equal(t, float64(1000), enter(t, root, "int").Float())
+	assert.Equal(t, int64(0xdeadbeef), enter(t, root, "hex").Int())
+	assert.Equal(t, int64(0755), enter(t, root, "oct").Int())
+	assert.Equal(t, int64(13), enter(t, root, "bin").Int())
+	assert.Equal(t, int64(-17), enter(t, root, "neg").Int())
+	assert.Equal(t, 6.626e-34, enter(t, root, "float").Float())
+	assert.True(t, enter(t, root, "bool").Bool())
+	assert.Equal(t, "1979-05-27T07:32:00-08:00", enter(t, root, "odt").String())
+	assert.Equal(t, "1979-05-27 07:32:00.999", enter(t, root, "ldt").String())
+	assert.Equal(t, "1979-05-27", enter(t, root, "ld").String())
+	assert.Equal(t, "07:32:00", enter(t, root, "lt").String())
+	assert.Equal(t, 3, enter(t, root, "array").ListLen())
+	assert.Equal(t, "four", enter(t, root, "array", "2", "1").String())
+	assert.Equal(t, int64(1), enter(t, root, "quoted key").Int())
+	assert.True(t, enter(t, root, "site", "google.com").Bool())
+}
+
+func TestMerge_Tables(t *testing.T) {
+	toml := `
+name = "root"
+
+[server]
+host = "localhost"
+port.http = 80
+
+[server.tls]
+enabled = true
+
+[[pool]]
+host = "a"
+[pool.options]
+size = 1
+
+[[pool]]
+host = "b"
+inline = { x = 1, y.z = 2 }
+
+[a.b.c]
+d = 1
+[a]
+e = 2
+`
+	root := tree.NewNode()
+	err := MergeString(root, toml, 1)
+	assert.Nil(t, err)
+
+	assert.Equal(t, "root", enter(t, root, "name").String())
+	assert.Equal(t, "localhost", enter(t, root, "server", "host").String())
+	assert.Equal(t, int64(80), enter(t, root, "server", "port", "http").Int())
+	assert.True(t, enter(t, root, "server", "tls", "enabled").Bool())
+	assert.Equal(t, 2, enter(t, root, "pool").ListLen())
+	assert.Equal(t, "a", enter(t, root, "pool", "0", "host").String())
+	assert.Equal(t, int64(1), enter(t, root, "pool", "0", "options", "size").Int())
+	assert.Equal(t, "b", enter(t, root, "pool", "1", "host").String())
+	assert.Equal(t, int64(2), enter(t, root, "pool", "1", "inline", "y", "z").Int())
+	assert.Equal(t, int64(1), enter(t, root, "a", "b", "c", "d").Int())
+	assert.Equal(t, int64(2), enter(t, root, "a", "e").Int())
+}
+
+func TestMerge_Errors(t *testing.T) {
+	cases := map[string]interface{}{
+		"a = 1\na = 2":              &DuplicateKeyError{},
+		"[a]\n[a]":                  &DuplicateKeyError{},
+		"a.b = 1\n[a]":              &DuplicateKeyError{},
+		"a = [1]\n[[a]]":            &DuplicateKeyError{},
+		"a = {b = 1}\n[a.c]":        &SyntaxError{},
+		"a = 1\n[a.b]":              &SyntaxError{},
+		"a = ":                      &SyntaxError{},
+		"a = 01":                    &SyntaxError{},
+		"a = \"unterminated":        &SyntaxError{},
+		"a = [1, 2":                 &SyntaxError{},
+		"a = 1 b = 2":               &SyntaxError{},
+		"a = \"\\q\"":               &SyntaxError{},
+		"a = 9223372036854775808":   &SyntaxError{},
+		"[a\nb = 1":                 &SyntaxError{},
+		"a = { b = 1, b = 2 }":      &DuplicateKeyError{},
+		"a = yes":                   &SyntaxError{},
+		"a = 1979-05-27T07:32:00Zx": &SyntaxError{},
+	}
+	for toml, expect := range cases {
+		err := MergeString(tree.NewNode(), toml, 1)
+		assert.IsType(t, expect, err, toml)
+	}
+
+	err := MergeString(tree.NewNode(), "a = 1\nb = [\n  1,\n  2 3]", 1)
+	assert.Equal(t, &SyntaxError{Line: 4, Row: 5, Message: "unexpected character '3'"}, err)
+}
+
+func TestMerge_Refill(t *testing.T) {
+	type Host struct {
+		Host string
+		Port int
+	}
+	type Config struct {
+		Name  string
+		Ratio float64
+		Hosts []Host
+		Tags  map[string]*Host
+	}
+	obj := Config{
+		Name:  "default",
+		Ratio: 1,
+		Hosts: []Host{{Host: "localhost", Port: 80}},
+		Tags: map[string]*Host{
+			"__prototype__": {Host: "proto", Port: 1},
+		},
+	}
+	toml := `
+Name = "cfgm"
+Ratio = 2
+
+[[Hosts]]
+Host = "a"
+
+[[Hosts]]
+Port = 8080
+
+[Tags.x]
+Port = 2
+`
+	root, err := obj2tree.BuildFrom(&obj, 1)
+	assert.Nil(t, err)
+	err = MergeString(root, toml, 2)
+	assert.Nil(t, err)
+	tree2obj.Refill(root, &obj, 1, 3)
+
+	expect := Config{
+		Name:  "cfgm",
+		Ratio: 2,
+		Hosts: []Host{{Host: "a", Port: 0}, {Host: "", Port: 8080}},
+		Tags: map[string]*Host{
+			"x": {Host: "proto", Port: 2},
+		},
+	}
+	assert.Equal(t, expect, obj)
+}
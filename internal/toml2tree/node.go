@@ -0,0 +1,65 @@
+package toml2tree
+
+import "strings"
+
+type valueKind int
+
+const (
+	kindScalar valueKind = iota
+	kindTable
+	kindArray
+)
+
+type tableOrigin int
+
+const (
+	originImplicit tableOrigin = iota // created as the parent of a table header
+	originHeader                      // defined by a table header
+	originDotted                      // created by a dotted key
+	originInline                      // defined by an inline table, which cannot be extended
+	originArray                       // an element of an array of tables
+)
+
+type tomlValue struct {
+	kind valueKind
+	line int
+	row  int
+
+	// scalar: int64, float64, bool or string
+	scalar interface{}
+
+	// table
+	table *tomlTable
+
+	// array
+	items         []*tomlValue
+	arrayOfTables bool
+}
+
+type tomlTable struct {
+	origin tableOrigin
+	keys   []string
+	values map[string]*tomlValue
+}
+
+func newTable(origin tableOrigin) *tomlTable {
+	return &tomlTable{
+		origin: origin,
+		keys:   make([]string, 0),
+		values: make(map[string]*tomlValue),
+	}
+}
+
+func (table *tomlTable) get(key string) (*tomlValue, bool) {
+	value, ok := table.values[key]
+	return value, ok
+}
+
+func (table *tomlTable) set(key string, value *tomlValue) {
+	table.keys = append(table.keys, key)
+	table.values[key] = value
+}
+
+func joinKey(keys []string) string {
+	return strings.Join(keys, ".")
+}
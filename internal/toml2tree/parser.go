@@ -0,0 +1,722 @@
+package toml2tree
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+var (
+	decimalPattern  = regexp.MustCompile(`^[-+]?(0|[1-9](_?[0-9])*)$`)
+	hexPattern      = regexp.MustCompile(`^0x[0-9A-Fa-f](_?[0-9A-Fa-f])*$`)
+	octPattern      = regexp.MustCompile(`^0o[0-7](_?[0-7])*$`)
+	binPattern      = regexp.MustCompile(`^0b[01](_?[01])*$`)
+	floatPattern    = regexp.MustCompile(`^[-+]?(0|[1-9](_?[0-9])*)((\.[0-9](_?[0-9])*)([eE][-+]?[0-9](_?[0-9])*)?|[eE][-+]?[0-9](_?[0-9])*)$`)
+	datePattern     = regexp.MustCompile(`^[0-9]{4}-[0-9]{2}-[0-9]{2}$`)
+	datetimePattern = regexp.MustCompile(`^[0-9]{4}-[0-9]{2}-[0-9]{2}([Tt ][0-9]{2}:[0-9]{2}(:[0-9]{2}(\.[0-9]+)?)?([Zz]|[-+][0-9]{2}:[0-9]{2})?)?$`)
+	timePattern     = regexp.MustCompile(`^[0-9]{2}:[0-9]{2}(:[0-9]{2}(\.[0-9]+)?)?$`)
+)
+
+/*
+parser builds the tables of a TOML document.
+
+Supported:
+
+	tables, arrays of tables, inline tables and dotted keys
+	basic, literal and multi-line strings
+	integers (decimal, hexadecimal, octal and binary), floats and booleans
+	offset date-times, local date-times, local dates and local times, which are kept as strings
+*/
+type parser struct {
+	src        []rune
+	pos        int
+	lineStarts []int
+	root       *tomlTable
+	current    *tomlTable
+}
+
+func newParser(src []rune) *parser {
+	lineStarts := []int{0}
+	for i, char := range src {
+		if char == '\n' {
+			lineStarts = append(lineStarts, i+1)
+		}
+	}
+	root := newTable(originHeader)
+	return &parser{
+		src:        src,
+		pos:        0,
+		lineStarts: lineStarts,
+		root:       root,
+		current:    root,
+	}
+}
+
+// <<==== utils begin ====>
+
+func isBlank(char rune) bool {
+	return char == ' ' || char == '\t'
+}
+
+func isBareKeyChar(char rune) bool {
+	return ('A' <= char && char <= 'Z') ||
+		('a' <= char && char <= 'z') ||
+		('0' <= char && char <= '9') ||
+		char == '_' || char == '-'
+}
+
+func (p *parser) position(pos int) (line int, row int) {
+	index := sort.Search(len(p.lineStarts), func(i int) bool {
+		return p.lineStarts[i] > pos
+	}) - 1
+	return index + 1, pos - p.lineStarts[index] + 1
+}
+
+func (p *parser) eof() bool {
+	return p.pos >= len(p.src)
+}
+
+func (p *parser) peekAt(offset int) rune {
+	if p.pos+offset >= len(p.src) {
+		return 0
+	}
+	return p.src[p.pos+offset]
+}
+
+func (p *parser) peek() rune {
+	return p.peekAt(0)
+}
+
+func (p *parser) hasPrefix(prefix string) bool {
+	for i, char := range []rune(prefix) {
+		if p.peekAt(i) != char {
+			return false
+		}
+	}
+	return true
+}
+
+func (p *parser) errorAt(pos int, format string, args ...interface{}) error {
+	line, row := p.position(pos)
+	return &SyntaxError{
+		Line:    line,
+		Row:     row,
+		Message: fmt.Sprintf(format, args...),
+	}
+}
+
+func (p *parser) errorf(format string, args ...interface{}) error {
+	return p.errorAt(p.pos, format, args...)
+}
+
+func (p *parser) unexpectError() error {
+	if p.eof() {
+		return p.errorf("unexpected end of file")
+	}
+	if p.peek() == '\n' {
+		return p.errorf("unexpected end of line")
+	}
+	return p.errorf("unexpected character '%c'", p.peek())
+}
+
+func (p *parser) duplicateError(pos int, keys []string) error {
+	line, row := p.position(pos)
+	return &DuplicateKeyError{
+		Line: line,
+		Row:  row,
+		Key:  joinKey(keys),
+	}
+}
+
+func (p *parser) newValue(kind valueKind, pos int) *tomlValue {
+	line, row := p.position(pos)
+	return &tomlValue{
+		kind: kind,
+		line: line,
+		row:  row,
+	}
+}
+
+func (p *parser) newTableValue(table *tomlTable, pos int) *tomlValue {
+	value := p.newValue(kindTable, pos)
+	value.table = table
+	return value
+}
+
+func (p *parser) skipBlanks() {
+	for isBlank(p.peek()) {
+		p.pos++
+	}
+}
+
+func (p *parser) skipComment() {
+	if p.peek() != '#' {
+		return
+	}
+	for !p.eof() && p.peek() != '\n' {
+		p.pos++
+	}
+}
+
+/*
+skipSpace skips blanks, comments and line breaks, which is allowed between the elements of arrays.
+*/
+func (p *parser) skipSpace() {
+	for {
+		p.skipBlanks()
+		p.skipComment()
+		if p.peek() != '\n' {
+			return
+		}
+		p.pos++
+	}
+}
+
+func (p *parser) expectLineEnd() error {
+	p.skipBlanks()
+	p.skipComment()
+	if p.eof() {
+		return nil
+	}
+	if p.peek() != '\n' {
+		return p.unexpectError()
+	}
+	p.pos++
+	return nil
+}
+
+// <----- utils end ----->
+
+// <<==== document begin ====>
+
+func (p *parser) parseDocument() (*tomlTable, error) {
+	if p.peek() == '\uFEFF' {
+		p.pos++
+	}
+	for {
+		p.skipSpace()
+		if p.eof() {
+			return p.root, nil
+		}
+		var err error
+		if p.hasPrefix("[[") {
+			err = p.parseArrayTableHeader()
+		} else if p.peek() == '[' {
+			err = p.parseTableHeader()
+		} else {
+			err = p.parseKeyValue(p.current)
+		}
+		if err != nil {
+			return nil, err
+		}
+		if err = p.expectLineEnd(); err != nil {
+			return nil, err
+		}
+	}
+}
+
+/*
+enterTable finds the parent table for a table header, the tables which do not exist will be
+created implicitly, and the last element will be used for an array of tables.
+*/
+func (p *parser) enterTable(keys []string, pos int) (*tomlTable, error) {
+	table := p.root
+	for i, key := range keys {
+		value, ok := table.get(key)
+		if !ok {
+			next := newTable(originImplicit)
+			table.set(key, p.newTableValue(next, pos))
+			table = next
+			continue
+		}
+		switch {
+		case value.kind == kindTable && value.table.origin != originInline:
+			table = value.table
+		case value.kind == kindArray && value.arrayOfTables:
+			table = value.items[len(value.items)-1].table
+		default:
+			return nil, p.errorAt(pos, "key (%s) is not a table", joinKey(keys[:i+1]))
+		}
+	}
+	return table, nil
+}
+
+func (p *parser) parseTableHeader() error {
+	start := p.pos
+	// '['
+	p.pos++
+	keys, err := p.parseKey()
+	if err != nil {
+		return err
+	}
+	if p.peek() != ']' {
+		return p.unexpectError()
+	}
+	p.pos++
+
+	parent, err := p.enterTable(keys[:len(keys)-1], start)
+	if err != nil {
+		return err
+	}
+	last := keys[len(keys)-1]
+	value, ok := parent.get(last)
+	if !ok {
+		table := newTable(originHeader)
+		parent.set(last, p.newTableValue(table, start))
+		p.current = table
+		return nil
+	}
+	if value.kind == kindTable && value.table.origin == originImplicit {
+		value.table.origin = originHeader
+		p.current = value.table
+		return nil
+	}
+	return p.duplicateError(start, keys)
+}
+
+func (p *parser) parseArrayTableHeader() error {
+	start := p.pos
+	// "[["
+	p.pos += 2
+	keys, err := p.parseKey()
+	if err != nil {
+		return err
+	}
+	if !p.hasPrefix("]]") {
+		return p.unexpectError()
+	}
+	p.pos += 2
+
+	parent, err := p.enterTable(keys[:len(keys)-1], start)
+	if err != nil {
+		return err
+	}
+	last := keys[len(keys)-1]
+	value, ok := parent.get(last)
+	if !ok {
+		value = p.newValue(kindArray, start)
+		value.arrayOfTables = true
+		parent.set(last, value)
+	} else if value.kind != kindArray || !value.arrayOfTables {
+		return p.duplicateError(start, keys)
+	}
+	table := newTable(originArray)
+	value.items = append(value.items, p.newTableValue(table, start))
+	p.current = table
+	return nil
+}
+
+// <----- document end ----->
+
+// <<==== key-value begin ====>
+
+func (p *parser) parseKey() ([]string, error) {
+	keys := make([]string, 0)
+	for {
+		p.skipBlanks()
+		var key string
+		var err error
+		switch char := p.peek(); {
+		case char == '"':
+			key, err = p.parseBasicString()
+		case char == '\'':
+			key, err = p.parseLiteralString()
+		case isBareKeyChar(char):
+			start := p.pos
+			for isBareKeyChar(p.peek()) {
+				p.pos++
+			}
+			key = string(p.src[start:p.pos])
+		default:
+			return nil, p.unexpectError()
+		}
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+		p.skipBlanks()
+		if p.peek() != '.' {
+			return keys, nil
+		}
+		p.pos++
+	}
+}
+
+/*
+parseKeyValue parses "key = value" and puts the value into table, the dotted key creates the
+tables which do not exist.
+*/
+func (p *parser) parseKeyValue(table *tomlTable) error {
+	start := p.pos
+	keys, err := p.parseKey()
+	if err != nil {
+		return err
+	}
+	if p.peek() != '=' {
+		return p.unexpectError()
+	}
+	p.pos++
+	p.skipBlanks()
+	value, err := p.parseValue()
+	if err != nil {
+		return err
+	}
+
+	for i, key := range keys[:len(keys)-1] {
+		next, ok := table.get(key)
+		if !ok {
+			sub := newTable(originDotted)
+			table.set(key, p.newTableValue(sub, start))
+			table = sub
+			continue
+		}
+		if next.kind != kindTable || (next.table.origin != originDotted && next.table.origin != originImplicit) {
+			return p.duplicateError(start, keys[:i+1])
+		}
+		table = next.table
+	}
+	last := keys[len(keys)-1]
+	if _, ok := table.get(last); ok {
+		return p.duplicateError(start, keys)
+	}
+	// the position of the key is more useful than the one of the value when reporting the source
+	value.line, value.row = p.position(start)
+	table.set(last, value)
+	return nil
+}
+
+// <----- key-value end ----->
+
+// <<==== value begin ====>
+
+func (p *parser) parseValue() (*tomlValue, error) {
+	start := p.pos
+	switch p.peek() {
+	case '"':
+		var str string
+		var err error
+		if p.hasPrefix(`"""`) {
+			str, err = p.parseMultiLineBasicString()
+		} else {
+			str, err = p.parseBasicString()
+		}
+		if err != nil {
+			return nil, err
+		}
+		return p.newScalar(start, str), nil
+	case '\'':
+		var str string
+		var err error
+		if p.hasPrefix(`'''`) {
+			str, err = p.parseMultiLineLiteralString()
+		} else {
+			str, err = p.parseLiteralString()
+		}
+		if err != nil {
+			return nil, err
+		}
+		return p.newScalar(start, str), nil
+	case '[':
+		return p.parseArray()
+	case '{':
+		return p.parseInlineTable()
+	case 0, '\n', '#':
+		return nil, p.errorf("missing value")
+	}
+	return p.parseToken()
+}
+
+func (p *parser) newScalar(pos int, scalar interface{}) *tomlValue {
+	value := p.newValue(kindScalar, pos)
+	value.scalar = scalar
+	return value
+}
+
+func (p *parser) scanToken() string {
+	start := p.pos
+	for !p.eof() {
+		char := p.peek()
+		if isBlank(char) || char == '\n' || char == ',' || char == ']' || char == '}' || char == '#' {
+			break
+		}
+		p.pos++
+	}
+	return string(p.src[start:p.pos])
+}
+
+/*
+parseToken parses booleans, numbers and date-times.
+*/
+func (p *parser) parseToken() (*tomlValue, error) {
+	start := p.pos
+	token := p.scanToken()
+	if datePattern.MatchString(token) && p.peek() == ' ' &&
+		'0' <= p.peekAt(1) && p.peekAt(1) <= '9' &&
+		'0' <= p.peekAt(2) && p.peekAt(2) <= '9' &&
+		p.peekAt(3) == ':' {
+		// date-time separated by space
+		p.pos++
+		token += " " + p.scanToken()
+	}
+
+	switch token {
+	case "true":
+		return p.newScalar(start, true), nil
+	case "false":
+		return p.newScalar(start, false), nil
+	case "inf", "+inf":
+		return p.newScalar(start, math.Inf(1)), nil
+	case "-inf":
+		return p.newScalar(start, math.Inf(-1)), nil
+	case "nan", "+nan", "-nan":
+		return p.newScalar(start, math.NaN()), nil
+	}
+
+	digits := strings.ReplaceAll(token, "_", "")
+	switch {
+	case decimalPattern.MatchString(token):
+		if i, err := strconv.ParseInt(digits, 10, 64); err == nil {
+			return p.newScalar(start, i), nil
+		}
+		return nil, p.errorAt(start, "integer (%s) out of range", token)
+	case hexPattern.MatchString(token), octPattern.MatchString(token), binPattern.MatchString(token):
+		if i, err := strconv.ParseInt(digits, 0, 64); err == nil {
+			return p.newScalar(start, i), nil
+		}
+		return nil, p.errorAt(start, "integer (%s) out of range", token)
+	case floatPattern.MatchString(token):
+		if f, err := strconv.ParseFloat(digits, 64); err == nil {
+			return p.newScalar(start, f), nil
+		}
+		return nil, p.errorAt(start, "invalid float (%s)", token)
+	case datetimePattern.MatchString(token), timePattern.MatchString(token):
+		return p.newScalar(start, token), nil
+	}
+	return nil, p.errorAt(start, "invalid value (%s)", token)
+}
+
+func (p *parser) parseArray() (*tomlValue, error) {
+	value := p.newValue(kindArray, p.pos)
+	value.items = make([]*tomlValue, 0)
+	// '['
+	p.pos++
+	for {
+		p.skipSpace()
+		if p.peek() == ']' {
+			p.pos++
+			return value, nil
+		}
+		item, err := p.parseValue()
+		if err != nil {
+			return nil, err
+		}
+		value.items = append(value.items, item)
+		p.skipSpace()
+		switch p.peek() {
+		case ',':
+			p.pos++
+		case ']':
+			p.pos++
+			return value, nil
+		default:
+			return nil, p.unexpectError()
+		}
+	}
+}
+
+func (p *parser) parseInlineTable() (*tomlValue, error) {
+	value := p.newValue(kindTable, p.pos)
+	value.table = newTable(originDotted)
+	// '{'
+	p.pos++
+	p.skipBlanks()
+	if p.peek() == '}' {
+		p.pos++
+		value.table.origin = originInline
+		return value, nil
+	}
+	for {
+		if err := p.parseKeyValue(value.table); err != nil {
+			return nil, err
+		}
+		p.skipBlanks()
+		switch p.peek() {
+		case ',':
+			p.pos++
+			p.skipBlanks()
+		case '}':
+			p.pos++
+			value.table.origin = originInline
+			return value, nil
+		default:
+			return nil, p.unexpectError()
+		}
+	}
+}
+
+// <----- value end ----->
+
+// <<==== string begin ====>
+
+func (p *parser) parseBasicString() (string, error) {
+	// '"'
+	p.pos++
+	builder := strings.Builder{}
+	for {
+		char := p.peek()
+		switch {
+		case p.eof() || char == '\n':
+			return "", p.errorf("unterminated string")
+		case char == '"':
+			p.pos++
+			return builder.String(), nil
+		case char == '\\':
+			if err := p.parseEscape(&builder); err != nil {
+				return "", err
+			}
+		default:
+			builder.WriteRune(char)
+			p.pos++
+		}
+	}
+}
+
+func (p *parser) parseMultiLineBasicString() (string, error) {
+	// `"""`
+	p.pos += 3
+	if p.peek() == '\n' {
+		p.pos++
+	}
+	builder := strings.Builder{}
+	for {
+		char := p.peek()
+		switch {
+		case p.eof():
+			return "", p.errorf("unterminated string")
+		case p.hasPrefix(`"""`):
+			// at most two quotes are allowed just before the delimiter
+			for i := 0; i < 2 && p.hasPrefix(`""""`); i++ {
+				builder.WriteRune('"')
+				p.pos++
+			}
+			p.pos += 3
+			return builder.String(), nil
+		case char == '\\' && p.isLineEndingBackslash():
+			p.pos++
+			p.skipSpaceInString()
+		case char == '\\':
+			if err := p.parseEscape(&builder); err != nil {
+				return "", err
+			}
+		default:
+			builder.WriteRune(char)
+			p.pos++
+		}
+	}
+}
+
+func (p *parser) isLineEndingBackslash() bool {
+	for i := 1; ; i++ {
+		char := p.peekAt(i)
+		if char == '\n' {
+			return true
+		}
+		if !isBlank(char) {
+			return false
+		}
+	}
+}
+
+func (p *parser) skipSpaceInString() {
+	for isBlank(p.peek()) || p.peek() == '\n' {
+		p.pos++
+	}
+}
+
+func (p *parser) parseLiteralString() (string, error) {
+	// '\''
+	p.pos++
+	start := p.pos
+	for p.peek() != '\'' {
+		if p.eof() || p.peek() == '\n' {
+			return "", p.errorf("unterminated string")
+		}
+		p.pos++
+	}
+	str := string(p.src[start:p.pos])
+	p.pos++
+	return str, nil
+}
+
+func (p *parser) parseMultiLineLiteralString() (string, error) {
+	// "'''"
+	p.pos += 3
+	if p.peek() == '\n' {
+		p.pos++
+	}
+	start := p.pos
+	for !p.hasPrefix("'''") {
+		if p.eof() {
+			return "", p.errorf("unterminated string")
+		}
+		p.pos++
+	}
+	// at most two quotes are allowed just before the delimiter
+	for i := 0; i < 2 && p.hasPrefix("''''"); i++ {
+		p.pos++
+	}
+	str := string(p.src[start:p.pos])
+	p.pos += 3
+	return str, nil
+}
+
+func (p *parser) parseEscape(builder *strings.Builder) error {
+	// '\\'
+	p.pos++
+	char := p.peek()
+	p.pos++
+	switch char {
+	case 'b':
+		builder.WriteRune('\b')
+	case 't':
+		builder.WriteRune('\t')
+	case 'n':
+		builder.WriteRune('\n')
+	case 'f':
+		builder.WriteRune('\f')
+	case 'r':
+		builder.WriteRune('\r')
+	case 'e':
+		builder.WriteRune('\x1b')
+	case '"', '\\':
+		builder.WriteRune(char)
+	case 'x':
+		return p.parseHexEscape(builder, 2)
+	case 'u':
+		return p.parseHexEscape(builder, 4)
+	case 'U':
+		return p.parseHexEscape(builder, 8)
+	default:
+		p.pos--
+		return p.errorf("invalid escape character '%c'", char)
+	}
+	return nil
+}
+
+func (p *parser) parseHexEscape(builder *strings.Builder, length int) error {
+	if p.pos+length > len(p.src) {
+		return p.errorf("unexpected end of file in escape sequence")
+	}
+	code, err := strconv.ParseUint(string(p.src[p.pos:p.pos+length]), 16, 32)
+	if err != nil {
+		return p.errorf("invalid escape sequence")
+	}
+	builder.WriteRune(rune(code))
+	p.pos += length
+	return nil
+}
+
+// <----- string end ----->
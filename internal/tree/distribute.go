@@ -14,7 +14,7 @@ func Distribute(node *Node, handler DistributeHandler) {
 		handler.HandleObj()
 	} else if node.Has(NodeKeyString) {
 		handler.HandleString()
-	} else if node.Has(NodeKeyInt) {
+	} else if node.Has(NodeKeyInt) || node.Has(NodeKeyUint) {
 		handler.HandleInt()
 	} else if node.Has(NodeKeyFloat) {
 		handler.HandleFloat()
@@ -30,7 +30,7 @@ func DistributeOnWalker(walker ReadonlyWalker, handler DistributeHandler) {
 		handler.HandleObj()
 	} else if walker.Has(NodeKeyString) {
 		handler.HandleString()
-	} else if walker.Has(NodeKeyInt) {
+	} else if walker.Has(NodeKeyInt) || walker.Has(NodeKeyUint) {
 		handler.HandleInt()
 	} else if walker.Has(NodeKeyFloat) {
 		handler.HandleFloat()
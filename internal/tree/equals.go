@@ -27,6 +27,9 @@ func fullNodeEquals(left, right *fullNode) bool {
 	if left.Has(NodeKeyInt) && left.intValue != right.intValue {
 		return false
 	}
+	if left.Has(NodeKeyUint) && left.uintValue != right.uintValue {
+		return false
+	}
 	if left.Has(NodeKeyFloat) && left.floatValue != right.floatValue {
 		return false
 	}
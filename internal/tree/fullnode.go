@@ -34,6 +34,10 @@ const (
 
 	flagClearObjWhenEnter
 	flagClearListWhenEnter
+
+	flagHasUint
+	flagIsNullUint
+	flagNullableUint
 )
 
 func (flag fullNodeFlag) has(target fullNodeFlag) bool {
@@ -57,6 +61,8 @@ func keyToHasFlag(key NodeKey) fullNodeFlag {
 		return flagHasDesc
 	case NodeKeyInt:
 		return flagHasInt
+	case NodeKeyUint:
+		return flagHasUint
 	case NodeKeyFloat:
 		return flagHasFloat
 	case NodeKeyBool:
@@ -82,6 +88,8 @@ func keyToIsNullFlag(key NodeKey) fullNodeFlag {
 		return flagIsNullDesc
 	case NodeKeyInt:
 		return flagIsNullInt
+	case NodeKeyUint:
+		return flagIsNullUint
 	case NodeKeyFloat:
 		return flagIsNullFloat
 	case NodeKeyBool:
@@ -103,6 +111,8 @@ func keyToNullableFlag(key NodeKey) fullNodeFlag {
 		return flagNullableDesc
 	case NodeKeyInt:
 		return flagNullableInt
+	case NodeKeyUint:
+		return flagNullableUint
 	case NodeKeyFloat:
 		return flagNullableFloat
 	case NodeKeyBool:
@@ -133,8 +143,18 @@ type fullNode struct {
 	flags      fullNodeFlag
 	modifyTime ModifyTime
 
+	// source describes where the value comes from, e.g. "config.json:3:5", it is not compared by Equals
+	source string
+
+	// level is the template level of the field, it is not compared by Equals
+	level Level
+
+	// secret means the value must be redacted when it is shown, it is not compared by Equals
+	secret bool
+
 	descValue   string
 	intValue    int64
+	uintValue   uint64
 	floatValue  float64
 	boolValue   bool
 	stringValue string
@@ -193,6 +213,18 @@ func (node *fullNode) ModifyTime() ModifyTime {
 	return node.modifyTime
 }
 
+func (node *fullNode) Source() string {
+	return node.source
+}
+
+func (node *fullNode) Level() Level {
+	return node.level
+}
+
+func (node *fullNode) Secret() bool {
+	return node.secret
+}
+
 func (node *fullNode) Desc() string {
 	return node.descValue
 }
@@ -201,6 +233,10 @@ func (node *fullNode) Int() int64 {
 	return node.intValue
 }
 
+func (node *fullNode) Uint() uint64 {
+	return node.uintValue
+}
+
 func (node *fullNode) Float() float64 {
 	return node.floatValue
 }
@@ -309,6 +345,21 @@ func (node *fullNode) SetModifyTime(time ModifyTime) InnerNode {
 	return node
 }
 
+func (node *fullNode) SetSource(source string) InnerNode {
+	node.source = source
+	return node
+}
+
+func (node *fullNode) SetLevel(level Level) InnerNode {
+	node.level = level
+	return node
+}
+
+func (node *fullNode) SetSecret(secret bool) InnerNode {
+	node.secret = secret
+	return node
+}
+
 func (node *fullNode) SetDesc(value string) InnerNode {
 	node.descValue = value
 	node.flags.add(flagHasDesc)
@@ -321,6 +372,12 @@ func (node *fullNode) SetInt(value int64) InnerNode {
 	return node
 }
 
+func (node *fullNode) SetUint(value uint64) InnerNode {
+	node.uintValue = value
+	node.flags.add(flagHasUint)
+	return node
+}
+
 func (node *fullNode) SetFloat(value float64) InnerNode {
 	node.floatValue = value
 	node.flags.add(flagHasFloat)
@@ -15,11 +15,14 @@ const (
 	NodeKeyList
 	NodeKeyObjPrototype
 	NodeKeyListPrototype
+	// NodeKeyUint holds the integers above math.MaxInt64, the others are held by NodeKeyInt.
+	NodeKeyUint
 )
 
 var NodeKeys = [...]NodeKey{
 	NodeKeyDesc,
 	NodeKeyInt,
+	NodeKeyUint,
 	NodeKeyFloat,
 	NodeKeyBool,
 	NodeKeyString,
@@ -33,6 +36,8 @@ func (key NodeKey) String() string {
 	switch key {
 	case NodeKeyInt:
 		return "NodeKeyInt"
+	case NodeKeyUint:
+		return "NodeKeyUint"
 	case NodeKeyFloat:
 		return "NodeKeyFloat"
 	case NodeKeyBool:
@@ -58,9 +63,13 @@ type ReadableInnerNode interface {
 	NullableFor(key NodeKey) bool
 	ClearWhenEnterFor(key NodeKey) bool
 	ModifyTime() ModifyTime
+	Source() string
+	Level() Level
+	Secret() bool
 
 	Desc() string
 	Int() int64
+	Uint() uint64
 	Float() float64
 	Bool() bool
 	String() string
@@ -77,9 +86,13 @@ type InnerNode interface {
 	SetNullableFor(key NodeKey, value bool) InnerNode
 	SetClearWhenEnterFor(key NodeKey, value bool) InnerNode
 	SetModifyTime(time ModifyTime) InnerNode
+	SetSource(source string) InnerNode
+	SetLevel(level Level) InnerNode
+	SetSecret(secret bool) InnerNode
 
 	SetDesc(value string) InnerNode
 	SetInt(value int64) InnerNode
+	SetUint(value uint64) InnerNode
 	SetFloat(value float64) InnerNode
 	SetBool(value bool) InnerNode
 	SetString(value string) InnerNode
@@ -101,9 +114,13 @@ type NodeWriter interface {
 	SetNullableFor(key NodeKey, value bool)
 	SetClearWhenEnterFor(key NodeKey, value bool)
 	SetModifyTime(time ModifyTime)
+	SetSource(source string)
+	SetLevel(level Level)
+	SetSecret(secret bool)
 
 	SetDesc(value string)
 	SetInt(value int64)
+	SetUint(value uint64)
 	SetFloat(value float64)
 	SetBool(value bool)
 	SetString(value string)
@@ -150,6 +167,18 @@ func (node *Node) ModifyTime() ModifyTime {
 	return node.Raw.ModifyTime()
 }
 
+func (node *Node) Source() string {
+	return node.Raw.Source()
+}
+
+func (node *Node) Level() Level {
+	return node.Raw.Level()
+}
+
+func (node *Node) Secret() bool {
+	return node.Raw.Secret()
+}
+
 func (node *Node) Desc() string {
 	return node.Raw.Desc()
 }
@@ -158,6 +187,10 @@ func (node *Node) Int() int64 {
 	return node.Raw.Int()
 }
 
+func (node *Node) Uint() uint64 {
+	return node.Raw.Uint()
+}
+
 func (node *Node) Float() float64 {
 	return node.Raw.Float()
 }
@@ -210,6 +243,18 @@ func (node *Node) SetModifyTime(time ModifyTime) {
 	node.Raw = node.Raw.SetModifyTime(time)
 }
 
+func (node *Node) SetSource(source string) {
+	node.Raw = node.Raw.SetSource(source)
+}
+
+func (node *Node) SetLevel(level Level) {
+	node.Raw = node.Raw.SetLevel(level)
+}
+
+func (node *Node) SetSecret(secret bool) {
+	node.Raw = node.Raw.SetSecret(secret)
+}
+
 func (node *Node) Delete(key NodeKey) {
 	node.Raw = node.Raw.Delete(key)
 }
@@ -222,6 +267,10 @@ func (node *Node) SetInt(value int64) {
 	node.Raw = node.Raw.SetInt(value)
 }
 
+func (node *Node) SetUint(value uint64) {
+	node.Raw = node.Raw.SetUint(value)
+}
+
 func (node *Node) SetFloat(value float64) {
 	node.Raw = node.Raw.SetFloat(value)
 }
@@ -0,0 +1,40 @@
+package tree
+
+/*
+Level grades the fields for the config template, so that a template can contain only the fields
+which matter. A node without level inherits the level of its parent, LevelCommon for the root.
+*/
+type Level int8
+
+const (
+	LevelNone Level = iota
+	LevelRequired
+	LevelCommon
+	LevelAdvanced
+)
+
+func (level Level) String() string {
+	switch level {
+	case LevelRequired:
+		return "required"
+	case LevelCommon:
+		return "common"
+	case LevelAdvanced:
+		return "advanced"
+	default:
+		return "none"
+	}
+}
+
+// ParseLevel parses "required", "common" or "advanced".
+func ParseLevel(str string) (Level, bool) {
+	switch str {
+	case "required":
+		return LevelRequired, true
+	case "common":
+		return LevelCommon, true
+	case "advanced":
+		return LevelAdvanced, true
+	}
+	return LevelNone, false
+}
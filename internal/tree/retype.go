@@ -0,0 +1,68 @@
+package tree
+
+var valueKeys = [...]NodeKey{
+	NodeKeyInt,
+	NodeKeyUint,
+	NodeKeyFloat,
+	NodeKeyBool,
+	NodeKeyString,
+	NodeKeyObj,
+	NodeKeyList,
+}
+
+/*
+KeepValues deletes the values of the current node of walker except the ones of keys, so that the
+node only keeps the type written by the latest layer, e.g. a string written to a node built from
+an int field makes the node a string node, and the type mismatch can be found when refilling.
+
+Only the existing values are deleted, so the modify time is not changed when nothing is deleted,
+which keeps the behavior of ClearWhenEnter.
+*/
+func KeepValues(walker Walker, keys ...NodeKey) {
+	for _, key := range valueKeys {
+		if !walker.Has(key) || containsKey(keys, key) {
+			continue
+		}
+		walker.Delete(key)
+	}
+}
+
+func containsKey(keys []NodeKey, target NodeKey) bool {
+	for _, key := range keys {
+		if key == target {
+			return true
+		}
+	}
+	return false
+}
+
+/*
+CopyValues replaces the values of dst with the copies of the values of src, and the other
+properties of dst are kept, e.g. the description and the prototypes.
+*/
+func CopyValues(dst, src *Node) {
+	copied := src.Copy(0)
+	for _, key := range valueKeys {
+		dst.Delete(key)
+		if !copied.Has(key) {
+			continue
+		}
+		switch key {
+		case NodeKeyInt:
+			dst.SetInt(copied.Int())
+		case NodeKeyUint:
+			dst.SetUint(copied.Uint())
+		case NodeKeyFloat:
+			dst.SetFloat(copied.Float())
+		case NodeKeyBool:
+			dst.SetBool(copied.Bool())
+		case NodeKeyString:
+			dst.SetString(copied.String())
+		case NodeKeyObj:
+			dst.SetObj(copied.Obj())
+		case NodeKeyList:
+			dst.SetList(copied.List())
+		}
+		dst.SetNullFor(key, copied.IsNullFor(key))
+	}
+}
@@ -0,0 +1,33 @@
+package tree
+
+// Redacted is shown instead of the values of the secret nodes.
+const Redacted = "******"
+
+/*
+MarkSecret marks the current node of walker and all its descendants as secret. The prototypes are
+marked too, so that the nodes created from them later are secret as well.
+*/
+func MarkSecret(walker Walker) {
+	walker.SetSecret(true)
+	for _, key := range walker.ObjKeys() {
+		if walker.TryEnterObj(key) {
+			MarkSecret(walker)
+			walker.Exit()
+		}
+	}
+	length := walker.ListLen()
+	for i := 0; i < length; i++ {
+		if walker.TryEnterList(i) {
+			MarkSecret(walker)
+			walker.Exit()
+		}
+	}
+	if walker.TryEnterObjPrototype() {
+		MarkSecret(walker)
+		walker.Exit()
+	}
+	if walker.TryEnterListPrototype() {
+		MarkSecret(walker)
+		walker.Exit()
+	}
+}
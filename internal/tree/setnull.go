@@ -0,0 +1,44 @@
+package tree
+
+/*
+SetNull marks the value of the current node of walker as null, if the value is nullable.
+*/
+func SetNull(walker Walker) {
+	handler := setNullDistributeHandler{walker: walker}
+	DistributeOnWalker(walker, &handler)
+}
+
+type setNullDistributeHandler struct {
+	walker Walker
+}
+
+func setNullIfNullable(walker Walker, key NodeKey) {
+	if walker.NullableFor(key) {
+		walker.SetNullFor(key, true)
+	}
+}
+
+func (s *setNullDistributeHandler) HandleInt() {
+	setNullIfNullable(s.walker, NodeKeyInt)
+	setNullIfNullable(s.walker, NodeKeyUint)
+}
+
+func (s *setNullDistributeHandler) HandleFloat() {
+	setNullIfNullable(s.walker, NodeKeyFloat)
+}
+
+func (s *setNullDistributeHandler) HandleBool() {
+	setNullIfNullable(s.walker, NodeKeyBool)
+}
+
+func (s *setNullDistributeHandler) HandleString() {
+	setNullIfNullable(s.walker, NodeKeyString)
+}
+
+func (s *setNullDistributeHandler) HandleObj() {
+	setNullIfNullable(s.walker, NodeKeyObj)
+}
+
+func (s *setNullDistributeHandler) HandleList() {
+	setNullIfNullable(s.walker, NodeKeyList)
+}
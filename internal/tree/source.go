@@ -0,0 +1,14 @@
+package tree
+
+import "fmt"
+
+/*
+FilePosition formats a position in a config file as a source of node, e.g. "config.json:3:5".
+The file name is omitted when it is empty.
+*/
+func FilePosition(file string, line, row int) string {
+	if len(file) == 0 {
+		return fmt.Sprintf("%d:%d", line, row)
+	}
+	return fmt.Sprintf("%s:%d:%d", file, line, row)
+}
@@ -146,6 +146,18 @@ func (walker *walker) ModifyTime() ModifyTime {
 	return walker.currentNode.ModifyTime()
 }
 
+func (walker *walker) Source() string {
+	return walker.currentNode.Source()
+}
+
+func (walker *walker) Level() Level {
+	return walker.currentNode.Level()
+}
+
+func (walker *walker) Secret() bool {
+	return walker.currentNode.Secret()
+}
+
 func (walker *walker) Desc() string {
 	return walker.currentNode.Desc()
 }
@@ -154,6 +166,10 @@ func (walker *walker) Int() int64 {
 	return walker.currentNode.Int()
 }
 
+func (walker *walker) Uint() uint64 {
+	return walker.currentNode.Uint()
+}
+
 func (walker *walker) Float() float64 {
 	return walker.currentNode.Float()
 }
@@ -341,12 +357,33 @@ func (walker *walker) SetModifyTime(time ModifyTime) {
 	walker.currentNode.SetModifyTime(time)
 }
 
+// SetSource only records where the value comes from, so it does not change any modify time.
+func (walker *walker) SetSource(source string) {
+	walker.currentNode.SetSource(source)
+}
+
+// SetLevel only describes the field for the template, so it does not change any modify time.
+func (walker *walker) SetLevel(level Level) {
+	walker.currentNode.SetLevel(level)
+}
+
+// SetSecret only describes how to show the value, so it does not change any modify time.
+func (walker *walker) SetSecret(secret bool) {
+	walker.currentNode.SetSecret(secret)
+}
+
 func (walker *walker) SetInt(value int64) {
 	walker.currentNode.SetInt(value)
 	walker.currentNode.SetModifyTime(walker.time)
 	walker.setModifyTimeForParentNodes()
 }
 
+func (walker *walker) SetUint(value uint64) {
+	walker.currentNode.SetUint(value)
+	walker.currentNode.SetModifyTime(walker.time)
+	walker.setModifyTimeForParentNodes()
+}
+
 func (walker *walker) SetFloat(value float64) {
 	walker.currentNode.SetFloat(value)
 	walker.currentNode.SetModifyTime(walker.time)
@@ -6,7 +6,31 @@ import (
 	"strings"
 )
 
+type DumpOptions struct {
+	/*
+		Level is the highest level of fields to dump, the fields of higher levels are commented
+		out. An object of a higher level is dumped when it contains any field to dump.
+		The fields without level inherit the level of their parents, tree.LevelCommon for the
+		root. All fields are dumped with tree.LevelNone.
+	*/
+	Level tree.Level
+
+	// Sources writes the source of each scalar as a trailing comment, e.g. `"Port": 80, // config.json:3:5`,
+	// and "default" for the nodes without source.
+	Sources bool
+
+	// SkipPrototypes omits the commented-out prototypes of maps and slices.
+	SkipPrototypes bool
+}
+
 func DumpToString(root *tree.Node) string {
+	return DumpToStringWithOptions(root, nil)
+}
+
+func DumpToStringWithOptions(root *tree.Node, options *DumpOptions) string {
+	if options == nil {
+		options = &DumpOptions{}
+	}
 	writer := stringBuilderWriter{
 		builder:        strings.Builder{},
 		level:          0,
@@ -14,14 +38,23 @@ func DumpToString(root *tree.Node) string {
 		endLineNeedNew: false,
 	}
 	env := dumpEnv{
-		json:   &writer,
-		walker: tree.ReadFrom(root),
+		json:     &writer,
+		walker:   tree.ReadFrom(root),
+		maxLevel: options.Level,
+		level:    tree.LevelCommon,
+
+		sources:        options.Sources,
+		skipPrototypes: options.SkipPrototypes,
 	}
 	env.dump()
 	return writer.builder.String()
 }
 
 func DumpToWriter(root *tree.Node, writer io.Writer) (int, error) {
-	str := DumpToString(root)
+	return DumpToWriterWithOptions(root, writer, nil)
+}
+
+func DumpToWriterWithOptions(root *tree.Node, writer io.Writer, options *DumpOptions) (int, error) {
+	str := DumpToStringWithOptions(root, options)
 	return writer.Write([]byte(str))
 }
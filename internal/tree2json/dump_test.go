@@ -2,8 +2,11 @@ package tree2json
 
 import (
 	"github.com/SnowPhoenix0105/cfgm/internal/obj2tree"
+	"github.com/SnowPhoenix0105/cfgm/internal/tree"
 	"github.com/stretchr/testify/assert"
+	"math"
 	"testing"
+	"time"
 )
 
 func TestAll(t *testing.T) {
@@ -75,3 +78,104 @@ func TestAll(t *testing.T) {
 	assert.Equal(t, 1, len(obj.Travel))
 	assert.Equal(t, 2, len(obj.Children))
 }
+
+func TestDumpWithLevel(t *testing.T) {
+	type DB struct {
+		Host    string `level:"required"`
+		Timeout int    `level:"advanced" desc:"in seconds"`
+	}
+	type Config struct {
+		Name  string `level:"required"`
+		Debug bool
+		DB    DB `level:"advanced"`
+		Pool  struct {
+			Size int
+		} `level:"advanced"`
+	}
+	root, err := obj2tree.BuildFrom(&Config{Name: "app"}, 1)
+	assert.Nil(t, err)
+
+	expect := `{
+	// "Debug": false,
+	// "Pool": {
+	// 	"Size": 0
+	// },
+	"DB": {
+		// in seconds
+		// "Timeout": 0,
+		"Host": ""
+	},
+	"Name": "app"
+}`
+	assert.Equal(t, expect, DumpToStringWithOptions(root, &DumpOptions{Level: tree.LevelRequired}))
+
+	expect = `{
+	// "Pool": {
+	// 	"Size": 0
+	// },
+	"DB": {
+		// in seconds
+		// "Timeout": 0,
+		"Host": ""
+	},
+	"Debug": false,
+	"Name": "app"
+}`
+	assert.Equal(t, expect, DumpToStringWithOptions(root, &DumpOptions{Level: tree.LevelCommon}))
+	assert.Equal(t, DumpToString(root), DumpToStringWithOptions(root, &DumpOptions{Level: tree.LevelAdvanced}))
+
+	type Invalid struct {
+		Name string `level:"important"`
+	}
+	_, err = obj2tree.BuildFrom(&Invalid{}, 1)
+	assert.Equal(t, &obj2tree.LevelError{Field: "Name", Level: "important"}, err)
+}
+
+func TestDumpTime(t *testing.T) {
+	type Server struct {
+		Timeout  time.Duration
+		Deadline time.Time
+	}
+	obj := &Server{
+		Timeout:  90 * time.Second,
+		Deadline: time.Date(2021, 6, 1, 12, 0, 0, 0, time.UTC),
+	}
+	root, err := obj2tree.BuildFrom(obj, 1)
+	assert.Nil(t, err)
+
+	expect := `{
+	"Deadline": "2021-06-01T12:00:00Z",
+	"Timeout": "1m30s"
+}`
+	assert.Equal(t, expect, DumpToString(root))
+}
+
+func TestDumpUnsigned(t *testing.T) {
+	type Limits struct {
+		Max  uint64
+		Port uint16
+	}
+	root, err := obj2tree.BuildFrom(&Limits{Max: math.MaxUint64, Port: 8080}, 1)
+	assert.Nil(t, err)
+
+	expect := `{
+	"Max": 18446744073709551615,
+	"Port": 8080
+}`
+	assert.Equal(t, expect, DumpToString(root))
+}
+
+func TestDumpEmpty(t *testing.T) {
+	type Server struct {
+		Hosts map[string]string
+		Tags  []string
+	}
+	root, err := obj2tree.BuildFrom(&Server{Tags: []string{}}, 1)
+	assert.Nil(t, err)
+
+	expect := `{
+	"Hosts": {},
+	"Tags": []
+}`
+	assert.Equal(t, expect, DumpToStringWithOptions(root, &DumpOptions{SkipPrototypes: true}))
+}
@@ -2,29 +2,140 @@ package tree2json
 
 import (
 	"github.com/SnowPhoenix0105/cfgm/internal/tree"
+	"sort"
 	"strconv"
 )
 
 type dumpEnv struct {
 	json   jsonWriter
 	walker tree.ReadonlyWalker
+
+	// maxLevel is the highest level to dump, see DumpOptions.Level
+	maxLevel tree.Level
+	// level is the inherited level of the current node
+	level tree.Level
+	// inComment is true when dumping the commented-out nodes, which are not filtered by level
+	inComment bool
+
+	// sources enables the trailing comments of sources, see DumpOptions.Sources
+	sources        bool
+	skipPrototypes bool
+}
+
+// annotate writes the source of the current node as a trailing comment if it is a scalar.
+func (env *dumpEnv) annotate() {
+	if !env.sources {
+		return
+	}
+	for _, key := range [...]tree.NodeKey{tree.NodeKeyObj, tree.NodeKeyList, tree.NodeKeyObjPrototype, tree.NodeKeyListPrototype} {
+		if env.walker.Has(key) {
+			return
+		}
+	}
+	source := env.walker.Source()
+	if len(source) == 0 {
+		source = "default"
+	}
+	env.json.WriteSpace()
+	env.json.WriteString("// " + source)
+}
+
+// levelOf returns the level of the current node, which inherits the level of its parent if unset.
+func (env *dumpEnv) levelOf(inherited tree.Level) tree.Level {
+	level := env.walker.Level()
+	if level == tree.LevelNone {
+		return inherited
+	}
+	return level
+}
+
+// visible reports whether the current node or any of its descendants should be dumped.
+func (env *dumpEnv) visible(inherited tree.Level) bool {
+	if env.maxLevel == tree.LevelNone || env.inComment {
+		return true
+	}
+	level := env.levelOf(inherited)
+	if level <= env.maxLevel {
+		return true
+	}
+	for _, key := range env.walker.ObjKeys() {
+		if !env.walker.TryEnterObj(key) {
+			continue
+		}
+		ok := env.visible(level)
+		env.walker.Exit()
+		if ok {
+			return true
+		}
+	}
+	length := env.walker.ListLen()
+	for i := 0; i < length; i++ {
+		if !env.walker.TryEnterList(i) {
+			continue
+		}
+		ok := env.visible(level)
+		env.walker.Exit()
+		if ok {
+			return true
+		}
+	}
+	return false
 }
 
 // <<<==== distribute begin ====>>>
 
+// dump writes null for a node without any value, e.g. built from a nil interface.
 func (env *dumpEnv) dump() {
+	if !hasValue(env.walker) {
+		env.json.WriteString("null")
+		return
+	}
 	tree.DistributeOnWalker(env.walker, env)
 }
 
+func hasValue(walker tree.ReadonlyWalker) bool {
+	for _, key := range []tree.NodeKey{
+		tree.NodeKeyObj, tree.NodeKeyObjPrototype, tree.NodeKeyList, tree.NodeKeyListPrototype,
+		tree.NodeKeyString, tree.NodeKeyInt, tree.NodeKeyUint, tree.NodeKeyFloat, tree.NodeKeyBool,
+	} {
+		if walker.Has(key) {
+			return true
+		}
+	}
+	return false
+}
+
+// redact writes tree.Redacted instead of the value of a secret node.
+func (env *dumpEnv) redact() bool {
+	if !env.walker.Secret() {
+		return false
+	}
+	env.dumpString(tree.Redacted)
+	return true
+}
+
 func (env *dumpEnv) HandleInt() {
-	env.json.WriteString(strconv.FormatInt(env.walker.Int(), 10))
+	if env.redact() {
+		return
+	}
+	if env.walker.Has(tree.NodeKeyInt) {
+		env.json.WriteString(strconv.FormatInt(env.walker.Int(), 10))
+	} else {
+		env.json.WriteString(strconv.FormatUint(env.walker.Uint(), 10))
+	}
 }
 
 func (env *dumpEnv) HandleFloat() {
+	if env.redact() {
+		return
+	}
 	env.json.WriteString(strconv.FormatFloat(env.walker.Float(), 'f', 3, 64))
 }
 
 func (env *dumpEnv) HandleBool() {
+	if env.redact() {
+		return
+	}
 	if env.walker.Bool() {
 		env.json.WriteString("true")
 	} else {
@@ -33,6 +144,9 @@ func (env *dumpEnv) HandleBool() {
 }
 
 func (env *dumpEnv) HandleString() {
+	if env.redact() {
+		return
+	}
 	env.dumpString(env.walker.String())
 }
 
@@ -88,12 +202,20 @@ func (env *dumpEnv) dumpString(str string) {
 }
 
 func (env *dumpEnv) dumpObj() {
+	// an empty object is dumped in one line
+	if len(env.walker.ObjKeys()) == 0 && (env.skipPrototypes || !env.walker.Has(tree.NodeKeyObjPrototype)) {
+		env.json.WriteString("{}")
+		return
+	}
+
 	env.json.WriteRune('{')
 	env.json.Enter()
 	env.json.EndLine()
 
 	// prototype
-	if env.walker.TryEnterObjPrototype() {
+	if !env.skipPrototypes && env.walker.TryEnterObjPrototype() {
+		inComment := env.inComment
+		env.inComment = true
 		env.json.StartComment()
 		env.dumpString("Key")
 		env.json.WriteRune(':')
@@ -101,15 +223,30 @@ func (env *dumpEnv) dumpObj() {
 		env.dump()
 		env.json.WriteRune(',')
 		env.json.EndComment()
+		env.inComment = inComment
 		env.walker.Exit()
 	}
 
-	// content
+	// content, the keys are sorted to make the output stable
 	keys := env.walker.ObjKeys()
-	for i, key := range keys {
-		if i != 0 {
-			env.json.WriteRune(',')
+	sort.Strings(keys)
+
+	// the fields above the level are commented out before the others, like the prototype
+	visibleKeys := make([]string, 0, len(keys))
+	for _, key := range keys {
+		if !env.walker.TryEnterObj(key) {
+			continue
+		}
+		if env.visible(env.level) {
+			visibleKeys = append(visibleKeys, key)
+			env.walker.Exit()
+			continue
 		}
+		env.dumpCommentedField(key)
+		env.walker.Exit()
+	}
+
+	for i, key := range visibleKeys {
 		env.json.EndLine()
 
 		ok := env.walker.TryEnterObj(key)
@@ -127,8 +264,15 @@ func (env *dumpEnv) dumpObj() {
 		env.json.WriteRune(':')
 		env.json.WriteSpace()
 
+		level := env.level
+		env.level = env.levelOf(level)
 		env.dump()
+		env.level = level
 
+		if i != len(visibleKeys)-1 {
+			env.json.WriteRune(',')
+		}
+		env.annotate()
 		env.walker.Exit()
 	}
 
@@ -137,26 +281,49 @@ func (env *dumpEnv) dumpObj() {
 	env.json.WriteRune('}')
 }
 
+func (env *dumpEnv) dumpCommentedField(key string) {
+	if env.walker.Has(tree.NodeKeyDesc) {
+		env.json.CommentAndNewLine(env.walker.Desc())
+	}
+	inComment := env.inComment
+	env.inComment = true
+	env.json.StartComment()
+	env.dumpString(key)
+	env.json.WriteRune(':')
+	env.json.WriteSpace()
+	env.dump()
+	env.json.WriteRune(',')
+	env.annotate()
+	env.json.EndComment()
+	env.inComment = inComment
+}
+
 func (env *dumpEnv) dumpList() {
+	// an empty list is dumped in one line
+	if env.walker.ListLen() == 0 && (env.skipPrototypes || !env.walker.Has(tree.NodeKeyListPrototype)) {
+		env.json.WriteString("[]")
+		return
+	}
+
 	env.json.WriteRune('[')
 	env.json.Enter()
 	env.json.EndLine()
 
 	// prototype
-	if env.walker.TryEnterListPrototype() {
+	if !env.skipPrototypes && env.walker.TryEnterListPrototype() {
+		inComment := env.inComment
+		env.inComment = true
 		env.json.StartComment()
 		env.dump()
 		env.json.WriteRune(',')
 		env.json.EndComment()
+		env.inComment = inComment
 		env.walker.Exit()
 	}
 
 	// content
 	length := env.walker.ListLen()
 	for i := 0; i < length; i++ {
-		if i != 0 {
-			env.json.WriteRune(',')
-		}
 		env.json.EndLine()
 		env.json.WriteSpace()
 
@@ -167,6 +334,10 @@ func (env *dumpEnv) dumpList() {
 			}
 		}
 		env.dump()
+		if i != length-1 {
+			env.json.WriteRune(',')
+		}
+		env.annotate()
 		env.walker.Exit()
 	}
 
@@ -0,0 +1,141 @@
+package tree2obj
+
+import (
+	"github.com/SnowPhoenix0105/cfgm/internal/tree"
+	"math"
+	"strconv"
+	"strings"
+)
+
+/*
+The conversions of the lenient mode:
+
+	int    <- string: parsed by strconv.ParseInt with base prefix, e.g. "0x1F"
+	int    <- float:  only when it is an integer
+	int    <- bool:   true is 1, false is 0
+	uint   <- string: parsed by strconv.ParseUint with base prefix, and the same as int for the others
+	float  <- int, string (parsed by strconv.ParseFloat), bool (1 or 0)
+	bool   <- string: parsed by strconv.ParseBool, e.g. "true", "1", "F"
+	bool   <- int, float: not zero is true
+	string <- int, float, bool: formatted by strconv
+
+The objects and the lists cannot be converted from or to any scalar.
+*/
+
+func convertToInt(node tree.NodeReader) (int64, bool) {
+	if node.Has(tree.NodeKeyString) {
+		i, err := strconv.ParseInt(strings.TrimSpace(node.String()), 0, 64)
+		return i, err == nil
+	}
+	if node.Has(tree.NodeKeyFloat) {
+		f := node.Float()
+		if f != math.Trunc(f) || f < math.MinInt64 || f >= math.MaxInt64 {
+			return 0, false
+		}
+		return int64(f), true
+	}
+	if node.Has(tree.NodeKeyBool) {
+		if node.Bool() {
+			return 1, true
+		}
+		return 0, true
+	}
+	return 0, false
+}
+
+func convertToUint(node tree.NodeReader) (uint64, bool) {
+	if node.Has(tree.NodeKeyString) {
+		u, err := strconv.ParseUint(strings.TrimSpace(node.String()), 0, 64)
+		return u, err == nil
+	}
+	if node.Has(tree.NodeKeyFloat) {
+		f := node.Float()
+		if f != math.Trunc(f) || f < 0 || f >= math.MaxUint64 {
+			return 0, false
+		}
+		return uint64(f), true
+	}
+	if node.Has(tree.NodeKeyBool) {
+		if node.Bool() {
+			return 1, true
+		}
+		return 0, true
+	}
+	return 0, false
+}
+
+func convertToFloat(node tree.NodeReader) (float64, bool) {
+	if node.Has(tree.NodeKeyString) {
+		f, err := strconv.ParseFloat(strings.TrimSpace(node.String()), 64)
+		return f, err == nil
+	}
+	if node.Has(tree.NodeKeyInt) {
+		return float64(node.Int()), true
+	}
+	if node.Has(tree.NodeKeyUint) {
+		return float64(node.Uint()), true
+	}
+	if node.Has(tree.NodeKeyBool) {
+		if node.Bool() {
+			return 1, true
+		}
+		return 0, true
+	}
+	return 0, false
+}
+
+func convertToBool(node tree.NodeReader) (bool, bool) {
+	if node.Has(tree.NodeKeyString) {
+		b, err := strconv.ParseBool(strings.TrimSpace(node.String()))
+		return b, err == nil
+	}
+	if node.Has(tree.NodeKeyInt) {
+		return node.Int() != 0, true
+	}
+	if node.Has(tree.NodeKeyUint) {
+		return node.Uint() != 0, true
+	}
+	if node.Has(tree.NodeKeyFloat) {
+		return node.Float() != 0, true
+	}
+	return false, false
+}
+
+func convertToString(node tree.NodeReader) (string, bool) {
+	if node.Has(tree.NodeKeyInt) {
+		return strconv.FormatInt(node.Int(), 10), true
+	}
+	if node.Has(tree.NodeKeyUint) {
+		return strconv.FormatUint(node.Uint(), 10), true
+	}
+	if node.Has(tree.NodeKeyFloat) {
+		return strconv.FormatFloat(node.Float(), 'g', -1, 64), true
+	}
+	if node.Has(tree.NodeKeyBool) {
+		return strconv.FormatBool(node.Bool()), true
+	}
+	return "", false
+}
+
+// formatNode formats the value of node for the errors, the secret value is redacted.
+func formatNode(node tree.NodeReader) string {
+	switch {
+	case node.Secret() && hasScalar(node):
+		return strconv.Quote(tree.Redacted)
+	case node.Has(tree.NodeKeyString):
+		return strconv.Quote(node.String())
+	case node.Has(tree.NodeKeyInt):
+		return strconv.FormatInt(node.Int(), 10)
+	case node.Has(tree.NodeKeyUint):
+		return strconv.FormatUint(node.Uint(), 10)
+	case node.Has(tree.NodeKeyFloat):
+		return strconv.FormatFloat(node.Float(), 'g', -1, 64)
+	case node.Has(tree.NodeKeyBool):
+		return strconv.FormatBool(node.Bool())
+	case node.Has(tree.NodeKeyObj):
+		return "an object"
+	case node.Has(tree.NodeKeyList):
+		return "a list"
+	}
+	return "nothing"
+}
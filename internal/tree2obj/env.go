@@ -1,44 +1,216 @@
 package tree2obj
 
 import (
+	"encoding"
 	"fmt"
+	"github.com/SnowPhoenix0105/cfgm/internal/check"
+	"github.com/SnowPhoenix0105/cfgm/internal/fields"
 	"github.com/SnowPhoenix0105/cfgm/internal/tree"
+	"net/url"
 	"reflect"
+	"strconv"
+	"strings"
+	"time"
 )
 
 type refillEnv struct {
-	walker    tree.ReadonlyWalker
-	buildTime tree.ModifyTime
+	walker     tree.ReadonlyWalker
+	buildTime  tree.ModifyTime
+	mode       Mode
+	naming     fields.Naming
+	path       []string
+	mismatches []*MismatchError
+}
+
+func (env *refillEnv) enter(name string) {
+	env.path = append(env.path, name)
+}
+
+func (env *refillEnv) exit() {
+	env.path = env.path[:len(env.path)-1]
+}
+
+// fieldMode returns the mode specified by the "cfgm" tag of field, or mode if not specified.
+func fieldMode(field reflect.StructField, mode Mode) Mode {
+	for _, item := range strings.Split(field.Tag.Get("cfgm"), ",") {
+		switch strings.TrimSpace(item) {
+		case fields.OptionStrict:
+			mode = ModeStrict
+		case fields.OptionLenient:
+			mode = ModeLenient
+		}
+	}
+	return mode
+}
+
+func hasScalar(node tree.NodeReader) bool {
+	return node.Has(tree.NodeKeyInt) ||
+		node.Has(tree.NodeKeyUint) ||
+		node.Has(tree.NodeKeyFloat) ||
+		node.Has(tree.NodeKeyBool) ||
+		node.Has(tree.NodeKeyString)
+}
+
+func hasValue(node tree.NodeReader) bool {
+	return hasScalar(node) || node.Has(tree.NodeKeyObj) || node.Has(tree.NodeKeyList)
+}
+
+func (env *refillEnv) mismatch(typ reflect.Type) {
+	env.mismatchWith(typ, nil)
+}
+
+// mismatchWith reports a mismatch with the error of parsing, which is dropped for the secrets.
+func (env *refillEnv) mismatchWith(typ reflect.Type, inner error) {
+	if env.walker.Secret() {
+		inner = nil
+	}
+	env.mismatches = append(env.mismatches, &MismatchError{
+		Path:   strings.Join(env.path, "."),
+		Type:   typ,
+		Value:  formatNode(env.walker),
+		Source: env.walker.Source(),
+		Inner:  inner,
+	})
+}
+
+/*
+refillScalar is called when the node does not hold the value of the type of obj. It is fine when
+the node holds nothing, otherwise the value is converted in ModeLenient, or reported as a mismatch.
+*/
+func (env *refillEnv) refillScalar(obj reflect.Value) {
+	if !hasValue(env.walker) {
+		return
+	}
+	if env.mode == ModeLenient {
+		switch obj.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			if i, ok := convertToInt(env.walker); ok {
+				env.setInt(obj, i)
+				return
+			}
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			if u, ok := convertToUint(env.walker); ok {
+				env.setUint(obj, u)
+				return
+			}
+		case reflect.Float32, reflect.Float64:
+			if f, ok := convertToFloat(env.walker); ok {
+				obj.SetFloat(f)
+				return
+			}
+		case reflect.Bool:
+			if b, ok := convertToBool(env.walker); ok {
+				obj.SetBool(b)
+				return
+			}
+		case reflect.String:
+			if str, ok := convertToString(env.walker); ok {
+				obj.SetString(str)
+				return
+			}
+		}
+	}
+	env.mismatch(obj.Type())
+}
+
+// setInt sets i to obj, or reports a mismatch if i is out of the range of the type of obj, e.g. int8.
+func (env *refillEnv) setInt(obj reflect.Value, i int64) {
+	if obj.OverflowInt(i) {
+		env.outOfRange(obj.Type())
+		return
+	}
+	obj.SetInt(i)
+}
+
+// setUint sets u to obj, or reports a mismatch if u is out of the range of the type of obj, e.g. uint8.
+func (env *refillEnv) setUint(obj reflect.Value, u uint64) {
+	if obj.OverflowUint(u) {
+		env.outOfRange(obj.Type())
+		return
+	}
+	obj.SetUint(u)
+}
+
+// outOfRange reports a mismatch with the range of typ, which is an integer type.
+func (env *refillEnv) outOfRange(typ reflect.Type) {
+	bits := uint(typ.Bits())
+	var err error
+	switch typ.Kind() {
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		err = fmt.Errorf("out of the range [0, %d]", ^uint64(0)>>(64-bits))
+	default:
+		err = fmt.Errorf("out of the range [%d, %d]", int64(-1)<<(bits-1), int64(^uint64(0)>>(65-bits)))
+	}
+	env.mismatchWith(typ, err)
+}
+
+// refillComposite is called when the node does not hold the object or the list for obj.
+func (env *refillEnv) refillComposite(obj reflect.Value) {
+	if hasScalar(env.walker) {
+		env.mismatch(obj.Type())
+	}
 }
 
 func (env *refillEnv) refill(obj reflect.Value) {
 	if env.walker.ModifyTime() == env.buildTime {
 		return
 	}
+	switch obj.Type() {
+	case check.DurationType:
+		env.refillDuration(obj)
+		return
+	case check.TimeType:
+		env.refillTime(obj)
+		return
+	case check.URLType:
+		env.refillURL(obj)
+		return
+	}
+	if check.IsTextType(obj.Type()) {
+		env.refillText(obj)
+		return
+	}
 	switch obj.Kind() {
 	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
 		if env.walker.Has(tree.NodeKeyInt) {
-			obj.SetInt(env.walker.Int())
+			env.setInt(obj, env.walker.Int())
+		} else if env.walker.Has(tree.NodeKeyUint) {
+			// always above math.MaxInt64
+			env.outOfRange(obj.Type())
+		} else {
+			env.refillScalar(obj)
 		}
 		return
 	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
-		if env.walker.Has(tree.NodeKeyInt) {
-			obj.SetUint(uint64(env.walker.Int()))
+		if env.walker.Has(tree.NodeKeyUint) {
+			env.setUint(obj, env.walker.Uint())
+		} else if !env.walker.Has(tree.NodeKeyInt) {
+			env.refillScalar(obj)
+		} else if i := env.walker.Int(); i < 0 {
+			env.outOfRange(obj.Type())
+		} else {
+			env.setUint(obj, uint64(i))
 		}
 		return
 	case reflect.Float32, reflect.Float64:
 		if env.walker.Has(tree.NodeKeyFloat) {
 			obj.SetFloat(env.walker.Float())
+		} else {
+			env.refillScalar(obj)
 		}
 		return
 	case reflect.Bool:
 		if env.walker.Has(tree.NodeKeyBool) {
 			obj.SetBool(env.walker.Bool())
+		} else {
+			env.refillScalar(obj)
 		}
 		return
 	case reflect.String:
 		if env.walker.Has(tree.NodeKeyString) {
 			obj.SetString(env.walker.String())
+		} else {
+			env.refillScalar(obj)
 		}
 		return
 	case reflect.Map:
@@ -50,12 +222,19 @@ func (env *refillEnv) refill(obj reflect.Value) {
 	case reflect.Slice:
 		env.refillSlice(obj)
 		return
+	case reflect.Array:
+		env.refillArray(obj)
+		return
+	case reflect.Interface:
+		env.refillInterface(obj)
+		return
 	}
 	panic("not implement")
 }
 
 func (env *refillEnv) refillMap(obj reflect.Value) {
 	if !env.walker.Has(tree.NodeKeyObj) {
+		env.refillComposite(obj)
 		return
 	}
 	mapType := obj.Type()
@@ -77,6 +256,7 @@ func (env *refillEnv) refillMap(obj reflect.Value) {
 			}
 			continue
 		}
+		env.enter(key)
 		keyReflect := reflect.ValueOf(key)
 		if valueTypeIsPtr {
 			ptr := obj.MapIndex(keyReflect)
@@ -85,6 +265,7 @@ func (env *refillEnv) refillMap(obj reflect.Value) {
 				if elem.IsValid() {
 					// merge by modify, and default value is provided
 					env.refill(elem)
+					env.exit()
 					env.walker.Exit()
 					continue
 				}
@@ -98,12 +279,27 @@ func (env *refillEnv) refillMap(obj reflect.Value) {
 		} else {
 			obj.SetMapIndex(keyReflect, elem)
 		}
+		env.exit()
 		env.walker.Exit()
 	}
+
+	if valueTypeIsPtr {
+		// remove the keys which have been removed from the tree
+		keySet := make(map[string]struct{}, len(keys))
+		for _, key := range keys {
+			keySet[key] = struct{}{}
+		}
+		for _, key := range obj.MapKeys() {
+			if _, ok := keySet[key.String()]; !ok {
+				obj.SetMapIndex(key, reflect.Value{})
+			}
+		}
+	}
 }
 
 func (env *refillEnv) refillSlice(obj reflect.Value) {
 	if !env.walker.Has(tree.NodeKeyList) {
+		env.refillComposite(obj)
 		return
 	}
 	sliceType := obj.Type()
@@ -131,6 +327,7 @@ func (env *refillEnv) refillSlice(obj reflect.Value) {
 			}
 			continue
 		}
+		env.enter("[" + strconv.Itoa(i) + "]")
 		if i < objLength {
 			ptr := obj.Index(i)
 			if ptr.IsValid() {
@@ -138,6 +335,7 @@ func (env *refillEnv) refillSlice(obj reflect.Value) {
 				if elem.IsValid() {
 					// merge by modify, and default value is provided
 					env.refill(elem)
+					env.exit()
 					env.walker.Exit()
 					continue
 				}
@@ -155,14 +353,238 @@ func (env *refillEnv) refillSlice(obj reflect.Value) {
 		} else {
 			obj.Set(reflect.Append(obj, elem))
 		}
+		env.exit()
+		env.walker.Exit()
+	}
+	if valueTypeIsPtr && objLength > length {
+		// remove the elements which have been removed from the tree
+		obj.SetLen(length)
+	}
+}
+
+/*
+refillArray refills the elements like refillSlice, and the elements which are not in the list are
+reset to zero. The elements beyond the length of array are reported as a mismatch of the array.
+*/
+func (env *refillEnv) refillArray(obj reflect.Value) {
+	if !env.walker.Has(tree.NodeKeyList) {
+		env.refillComposite(obj)
+		return
+	}
+	arrayType := obj.Type()
+	valueType := arrayType.Elem()
+	valueTypeIsPtr := valueType.Kind() == reflect.Ptr
+
+	length := env.walker.ListLen()
+	if length > obj.Len() {
+		env.mismatchWith(arrayType, fmt.Errorf("expect at most %d elements, but got %d", obj.Len(), length))
+		length = obj.Len()
+	}
+	for i := 0; i < obj.Len(); i++ {
+		elem := obj.Index(i)
+		if i >= length {
+			// remove the elements which are not in the tree
+			elem.Set(reflect.Zero(valueType))
+			continue
+		}
+		if !env.walker.TryEnterList(i) {
+			if DEBUG {
+				panic("TryEnterList() fail with index less than ListLen()")
+			}
+			continue
+		}
+		env.enter("[" + strconv.Itoa(i) + "]")
+		if !valueTypeIsPtr {
+			elem.Set(reflect.Zero(valueType))
+			env.refill(elem)
+		} else {
+			if elem.IsNil() {
+				// merge by modify when the default value is provided
+				elem.Set(reflect.New(valueType.Elem()))
+			}
+			env.refill(elem.Elem())
+		}
+		env.exit()
 		env.walker.Exit()
 	}
 }
 
-func (env *refillEnv) isNullFor(kind reflect.Kind) bool {
+/*
+refillInterface refills obj with a plain value built from the current node, which is one of
+map[string]interface{}, []interface{}, int64, uint64, float64, bool, string and nil.
+*/
+func (env *refillEnv) refillInterface(obj reflect.Value) {
+	if !hasValue(env.walker) {
+		return
+	}
+	value := dynamicValue(env.walker)
+	if value == nil {
+		obj.Set(reflect.Zero(obj.Type()))
+		return
+	}
+	valueReflect := reflect.ValueOf(value)
+	if !valueReflect.Type().AssignableTo(obj.Type()) {
+		env.mismatch(obj.Type())
+		return
+	}
+	obj.Set(valueReflect)
+}
+
+func dynamicValue(walker tree.ReadonlyWalker) interface{} {
+	switch {
+	case walker.Has(tree.NodeKeyObj):
+		if walker.IsNullFor(tree.NodeKeyObj) {
+			return nil
+		}
+		obj := make(map[string]interface{})
+		for _, key := range walker.ObjKeys() {
+			if walker.TryEnterObj(key) {
+				obj[key] = dynamicValue(walker)
+				walker.Exit()
+			}
+		}
+		return obj
+	case walker.Has(tree.NodeKeyList):
+		if walker.IsNullFor(tree.NodeKeyList) {
+			return nil
+		}
+		length := walker.ListLen()
+		list := make([]interface{}, length)
+		for i := 0; i < length; i++ {
+			if walker.TryEnterList(i) {
+				list[i] = dynamicValue(walker)
+				walker.Exit()
+			}
+		}
+		return list
+	case walker.Has(tree.NodeKeyInt):
+		if walker.IsNullFor(tree.NodeKeyInt) {
+			return nil
+		}
+		return walker.Int()
+	case walker.Has(tree.NodeKeyUint):
+		if walker.IsNullFor(tree.NodeKeyUint) {
+			return nil
+		}
+		return walker.Uint()
+	case walker.Has(tree.NodeKeyFloat):
+		if walker.IsNullFor(tree.NodeKeyFloat) {
+			return nil
+		}
+		return walker.Float()
+	case walker.Has(tree.NodeKeyBool):
+		if walker.IsNullFor(tree.NodeKeyBool) {
+			return nil
+		}
+		return walker.Bool()
+	case walker.Has(tree.NodeKeyString):
+		if walker.IsNullFor(tree.NodeKeyString) {
+			return nil
+		}
+		return walker.String()
+	}
+	return nil
+}
+
+/*
+refillDuration parses the string like "1m30s" by time.ParseDuration. The other values are reported
+as mismatches even in ModeLenient, since it is unclear which unit an integer is in.
+*/
+func (env *refillEnv) refillDuration(obj reflect.Value) {
+	if !env.walker.Has(tree.NodeKeyString) {
+		if hasValue(env.walker) {
+			env.mismatch(obj.Type())
+		}
+		return
+	}
+	duration, err := time.ParseDuration(strings.TrimSpace(env.walker.String()))
+	if err != nil {
+		env.mismatchWith(obj.Type(), err)
+		return
+	}
+	obj.SetInt(int64(duration))
+}
+
+// refillTime parses the string in RFC 3339, e.g. "2006-01-02T15:04:05Z" or "2006-01-02T15:04:05+08:00".
+func (env *refillEnv) refillTime(obj reflect.Value) {
+	if !env.walker.Has(tree.NodeKeyString) {
+		if hasValue(env.walker) {
+			env.mismatch(obj.Type())
+		}
+		return
+	}
+	t, err := time.Parse(time.RFC3339Nano, strings.TrimSpace(env.walker.String()))
+	if err != nil {
+		env.mismatchWith(obj.Type(), err)
+		return
+	}
+	obj.Set(reflect.ValueOf(t))
+}
+
+// refillURL parses the string by url.Parse, e.g. "https://example.com/path".
+func (env *refillEnv) refillURL(obj reflect.Value) {
+	if !env.walker.Has(tree.NodeKeyString) {
+		if hasValue(env.walker) {
+			env.mismatch(obj.Type())
+		}
+		return
+	}
+	u, err := url.Parse(strings.TrimSpace(env.walker.String()))
+	if err != nil {
+		env.mismatchWith(obj.Type(), err)
+		return
+	}
+	obj.Set(reflect.ValueOf(*u))
+}
+
+/*
+refillText parses the string by UnmarshalText, the scalars are converted to string in ModeLenient.
+The value is parsed into a new one, so obj is kept when UnmarshalText fails. The types which only
+implement TextMarshaler cannot be refilled.
+*/
+func (env *refillEnv) refillText(obj reflect.Value) {
+	if !hasValue(env.walker) {
+		return
+	}
+	ptr := reflect.New(obj.Type())
+	unmarshaler, ok := ptr.Interface().(encoding.TextUnmarshaler)
+	if !ok {
+		env.mismatch(obj.Type())
+		return
+	}
+	text, ok := "", env.walker.Has(tree.NodeKeyString)
+	if ok {
+		text = env.walker.String()
+	} else if env.mode == ModeLenient {
+		text, ok = convertToString(env.walker)
+	}
+	if !ok {
+		env.mismatch(obj.Type())
+		return
+	}
+	err := unmarshaler.UnmarshalText([]byte(text))
+	if err != nil {
+		env.mismatchWith(obj.Type(), err)
+		return
+	}
+	obj.Set(ptr.Elem())
+}
+
+func (env *refillEnv) isNullFor(typ reflect.Type) bool {
+	switch typ {
+	case check.DurationType, check.TimeType, check.URLType:
+		return env.walker.IsNullFor(tree.NodeKeyString)
+	}
+	if check.IsTextType(typ) {
+		return env.walker.IsNullFor(tree.NodeKeyString)
+	}
+	kind := typ.Kind()
 	switch kind {
 	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
 		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		if env.walker.Has(tree.NodeKeyUint) {
+			return env.walker.IsNullFor(tree.NodeKeyUint)
+		}
 		return env.walker.IsNullFor(tree.NodeKeyInt)
 	case reflect.Float32, reflect.Float64:
 		return env.walker.IsNullFor(tree.NodeKeyFloat)
@@ -172,14 +594,14 @@ func (env *refillEnv) isNullFor(kind reflect.Kind) bool {
 		return env.walker.IsNullFor(tree.NodeKeyString)
 	case reflect.Map, reflect.Struct:
 		return env.walker.IsNullFor(tree.NodeKeyObj)
-	case reflect.Slice:
+	case reflect.Slice, reflect.Array:
 		return env.walker.IsNullFor(tree.NodeKeyList)
 	}
 	panic(fmt.Sprintf("Invalid Kind: %s", kind.String()))
 }
 
 func (env *refillEnv) refillPtrField(ptr reflect.Value) {
-	if env.isNullFor(ptr.Type().Elem().Kind()) {
+	if env.isNullFor(ptr.Type().Elem()) {
 		ptr.Set(reflect.Zero(ptr.Type()))
 		return
 	}
@@ -192,7 +614,7 @@ func (env *refillEnv) refillPtrField(ptr reflect.Value) {
 }
 
 func (env *refillEnv) refillPtrPtrField(ptrptr reflect.Value) {
-	if env.isNullFor(ptrptr.Type().Elem().Elem().Kind()) {
+	if env.isNullFor(ptrptr.Type().Elem().Elem()) {
 		ptrptr.Set(reflect.Zero(ptrptr.Type()))
 		return
 	}
@@ -209,22 +631,35 @@ func (env *refillEnv) refillPtrPtrField(ptrptr reflect.Value) {
 	env.refill(elem)
 }
 
+/*
+refillStruct refills the fields promoted from the embedded structs in obj too, see fields.Of(). The
+nil embedded pointers are allocated only when the promoted fields are modified.
+*/
 func (env *refillEnv) refillStruct(obj reflect.Value) {
 	if !env.walker.Has(tree.NodeKeyObj) {
+		env.refillComposite(obj)
 		return
 	}
 
-	structType := obj.Type()
-	numField := obj.NumField()
-	for i := 0; i < numField; i++ {
-		field := structType.Field(i)
-		elem := obj.Field(i)
-		if !elem.CanSet() {
+	for _, field := range fields.Of(obj.Type(), env.naming) {
+		if len(field.StructField.PkgPath) != 0 {
+			// unexported
 			continue
 		}
 		if !env.walker.TryEnterObj(field.Name) {
 			continue
 		}
+		elem, ok := reflect.Value{}, false
+		if env.walker.ModifyTime() != env.buildTime {
+			elem, ok = fields.ByIndex(obj, field.Index, true)
+		}
+		if !ok || !elem.CanSet() {
+			env.walker.Exit()
+			continue
+		}
+		env.enter(field.Name)
+		mode := env.mode
+		env.mode = fieldMode(field.StructField, mode)
 		if elem.Kind() != reflect.Ptr {
 			// simple
 			env.refill(elem)
@@ -235,6 +670,8 @@ func (env *refillEnv) refillStruct(obj reflect.Value) {
 			// ptr to ptr
 			env.refillPtrPtrField(elem)
 		}
+		env.mode = mode
+		env.exit()
 		env.walker.Exit()
 	}
 }
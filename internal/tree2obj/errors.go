@@ -0,0 +1,50 @@
+package tree2obj
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+/*
+MismatchError describes a value which cannot be refilled to the field, Source is where the value
+comes from, e.g. "config.json:3:5". Inner is the error of parsing the value if any, e.g. the error
+returned by UnmarshalText.
+*/
+type MismatchError struct {
+	Path   string
+	Type   reflect.Type
+	Value  string
+	Source string
+	Inner  error
+}
+
+func (err *MismatchError) Error() string {
+	source := err.Source
+	if len(source) == 0 {
+		source = "default"
+	}
+	msg := fmt.Sprintf("%s cannot be %s, but got %s (from %s)", err.Path, err.Type.String(), err.Value, source)
+	if err.Inner != nil {
+		msg += ": " + err.Inner.Error()
+	}
+	return msg
+}
+
+func (err *MismatchError) Unwrap() error {
+	return err.Inner
+}
+
+type RefillError struct {
+	Mismatches []*MismatchError
+}
+
+func (err *RefillError) Error() string {
+	builder := strings.Builder{}
+	builder.WriteString(fmt.Sprintf("cfgm refill error: %d value(s) mismatch the types", len(err.Mismatches)))
+	for _, mismatch := range err.Mismatches {
+		builder.WriteString("\n\t")
+		builder.WriteString(mismatch.Error())
+	}
+	return builder.String()
+}
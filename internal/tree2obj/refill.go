@@ -1,29 +1,118 @@
 package tree2obj
 
 import (
+	"github.com/SnowPhoenix0105/cfgm/internal/check"
+	"github.com/SnowPhoenix0105/cfgm/internal/fields"
 	"github.com/SnowPhoenix0105/cfgm/internal/tree"
 	"reflect"
 )
 
+type Mode int
+
+const (
+	// ModeStrict reports a MismatchError when the type of value mismatches the field.
+	ModeStrict Mode = iota
+	// ModeLenient converts the value to the type of field, see convertToInt() and so on.
+	ModeLenient
+)
+
+/*
+Options of refilling, nil means the default options.
+
+The mode can be overridden by the "cfgm" tag of a field, e.g. `cfgm:"lenient"` or `cfgm:"strict"`,
+which takes effect on the field and the values nested in it.
+*/
+type Options struct {
+	Mode Mode
+
+	// Path is the path of the node to refill from, which prefixes the paths in MismatchError.
+	Path []string
+
+	// Naming converts the names of fields to the keys, which must be the same as the one building
+	// the tree, see obj2tree.Options.
+	Naming fields.Naming
+}
+
 func Refill(
 	root *tree.Node,
 	obj interface{},
 	buildTime tree.ModifyTime,
-	currentTime tree.ModifyTime) {
-	env := refillEnv{
-		walker:    tree.ReadFrom(root),
-		buildTime: buildTime,
-	}
-	env.refill(reflect.Indirect(reflect.ValueOf(obj)))
+	currentTime tree.ModifyTime) error {
+	return RefillFrom(tree.ReadFrom(root), obj, buildTime, nil)
 }
 
+/*
+RefillFrom refills obj with the values of the current node of walker, the nodes modified at
+buildTime are skipped. The values mismatching the types are skipped too, and all of them are
+reported with RefillError.
+
+The interface fields, e.g. interface{} and map[string]interface{}, are refilled with plain values
+built from the subtrees, which are map[string]interface{}, []interface{}, int64, float64, bool,
+string and nil, and uint64 only for the integers above math.MaxInt64.
+
+The integers out of the range of the fields, e.g. 300 for uint8, are reported with the
+MismatchError of the fields.
+
+The time.Duration fields are refilled from the strings like "1m30s", and the time.Time fields are
+refilled from the strings in RFC 3339, e.g. "2006-01-02T15:04:05Z". The url.URL fields are refilled
+from the strings by url.Parse, e.g. "https://example.com/path". The fields implementing
+encoding.TextUnmarshaler are refilled from the strings by UnmarshalText, and the errors of it are
+reported with the MismatchError of the field.
+
+The array fields are refilled like the slices, and the lists longer than the arrays are reported
+with the MismatchError of the field, while the elements in the range of arrays are still refilled.
+*/
 func RefillFrom(
 	walker tree.ReadonlyWalker,
 	obj interface{},
-	buildTime tree.ModifyTime) {
+	buildTime tree.ModifyTime,
+	options *Options) error {
+	if options == nil {
+		options = &Options{}
+	}
 	env := refillEnv{
-		walker:    walker,
-		buildTime: buildTime,
+		walker:     walker,
+		buildTime:  buildTime,
+		mode:       options.Mode,
+		naming:     options.Naming,
+		path:       append(make([]string, 0, len(options.Path)), options.Path...),
+		mismatches: make([]*MismatchError, 0),
 	}
 	env.refill(reflect.Indirect(reflect.ValueOf(obj)))
+	if len(env.mismatches) != 0 {
+		return &RefillError{Mismatches: env.mismatches}
+	}
+	return nil
+}
+
+/*
+CanRefill reports whether the current node of walker can be refilled to a value of typ, the
+scalars can be converted to each other in ModeLenient.
+*/
+func CanRefill(walker tree.ReadonlyWalker, typ reflect.Type, mode Mode) bool {
+	switch typ {
+	case check.DurationType, check.TimeType, check.URLType:
+		return walker.Has(tree.NodeKeyString)
+	}
+	if check.IsTextType(typ) {
+		return walker.Has(tree.NodeKeyString) || (mode == ModeLenient && hasScalar(walker))
+	}
+	switch typ.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return walker.Has(tree.NodeKeyInt) || walker.Has(tree.NodeKeyUint) || (mode == ModeLenient && hasScalar(walker))
+	case reflect.Float32, reflect.Float64:
+		return walker.Has(tree.NodeKeyFloat) || (mode == ModeLenient && hasScalar(walker))
+	case reflect.Bool:
+		return walker.Has(tree.NodeKeyBool) || (mode == ModeLenient && hasScalar(walker))
+	case reflect.String:
+		return walker.Has(tree.NodeKeyString) || (mode == ModeLenient && hasScalar(walker))
+	case reflect.Map, reflect.Struct:
+		return walker.Has(tree.NodeKeyObj) || walker.Has(tree.NodeKeyObjPrototype)
+	case reflect.Slice, reflect.Array:
+		return walker.Has(tree.NodeKeyList) || walker.Has(tree.NodeKeyListPrototype)
+	case reflect.Interface:
+		return hasValue(walker)
+	}
+	return false
 }
@@ -1,12 +1,21 @@
 package tree2obj
 
 import (
+	"fmt"
 	"github.com/SnowPhoenix0105/cfgm/internal/json2tree"
 	"github.com/SnowPhoenix0105/cfgm/internal/obj2tree"
+	"github.com/SnowPhoenix0105/cfgm/internal/tree"
 	"github.com/SnowPhoenix0105/cfgm/internal/tree2json"
 	"github.com/stretchr/testify/assert"
+	"math"
+	"math/big"
+	"net"
+	"net/url"
+	"reflect"
+	"sort"
 	"strings"
 	"testing"
+	"time"
 )
 
 func TestRefill_MapPrototype(t *testing.T) {
@@ -142,3 +151,418 @@ func TestRefill_MapPrototype(t *testing.T) {
 	}
 	assert.Equal(t, expect, obj)
 }
+
+func TestRefill_TypeMatching(t *testing.T) {
+	type Server struct {
+		Host    string
+		Port    int
+		Debug   bool
+		Ratio   float64
+		Retries int `cfgm:"lenient"`
+		Tags    []string
+	}
+	json := `
+{
+	"Host": 127,
+	"Port": "8080",
+	"Debug": 1,
+	"Ratio": 1,
+	"Retries": "3",
+	"Tags": [1, "b"]
+}`
+	build := func() (*Server, *tree.Node) {
+		obj := &Server{Port: 80, Tags: []string{}}
+		root, err := obj2tree.BuildFrom(obj, 1)
+		assert.Nil(t, err)
+		err = json2tree.MergeWithFileName(root, strings.NewReader(json), 2, "server.json")
+		assert.Nil(t, err)
+		return obj, root
+	}
+
+	obj, root := build()
+	err := RefillFrom(tree.ReadFrom(root), obj, 1, nil)
+	refillErr, ok := err.(*RefillError)
+	assert.True(t, ok)
+	paths := make([]string, 0)
+	for _, mismatch := range refillErr.Mismatches {
+		paths = append(paths, mismatch.Path)
+	}
+	assert.Equal(t, []string{"Host", "Port", "Debug", "Tags.[0]"}, paths)
+	assert.Equal(t, "Port cannot be int, but got \"8080\" (from server.json:4:2)", refillErr.Mismatches[1].Error())
+	assert.Equal(t, Server{Port: 80, Ratio: 1, Retries: 3, Tags: []string{"", "b"}}, *obj)
+
+	obj, root = build()
+	err = RefillFrom(tree.ReadFrom(root), obj, 1, &Options{Mode: ModeLenient})
+	assert.Nil(t, err)
+	assert.Equal(t, Server{Host: "127", Port: 8080, Debug: true, Ratio: 1, Retries: 3, Tags: []string{"1", "b"}}, *obj)
+}
+
+func TestRefill_Interface(t *testing.T) {
+	type Plugin struct {
+		Name    string
+		Options interface{}
+		Extra   map[string]interface{}
+		Args    []interface{}
+		Default interface{}
+		Null    interface{}
+	}
+	json := `
+{
+	"Options": {"Retry": 3, "Ratio": 0.5, "Tags": ["a", {"b": true}], "Empty": null},
+	"Extra": {"Key": "value", "Port": 80},
+	"Args": [1, "2", false],
+	"Null": null
+}`
+	obj := &Plugin{
+		Name:    "plugin",
+		Extra:   map[string]interface{}{"Debug": true},
+		Default: "default",
+		Null:    "not null",
+	}
+	root, err := obj2tree.BuildFrom(obj, 1)
+	assert.Nil(t, err)
+	err = json2tree.Merge(root, strings.NewReader(json), 2)
+	assert.Nil(t, err)
+	err = RefillFrom(tree.ReadFrom(root), obj, 1, nil)
+	assert.Nil(t, err)
+	assert.Equal(t, &Plugin{
+		Name: "plugin",
+		Options: map[string]interface{}{
+			"Retry": int64(3),
+			"Ratio": 0.5,
+			"Tags":  []interface{}{"a", map[string]interface{}{"b": true}},
+			"Empty": nil,
+		},
+		Extra:   map[string]interface{}{"Key": "value", "Port": int64(80)},
+		Args:    []interface{}{int64(1), "2", false},
+		Default: "default",
+	}, obj)
+
+	type Named struct {
+		Value fmt.Stringer
+	}
+	named := &Named{}
+	root, err = obj2tree.BuildFrom(named, 1)
+	assert.Nil(t, err)
+	err = json2tree.MergeWithFileName(root, strings.NewReader(`{"Value": "abc"}`), 2, "named.json")
+	assert.Nil(t, err)
+	err = RefillFrom(tree.ReadFrom(root), named, 1, nil)
+	refillErr, ok := err.(*RefillError)
+	assert.True(t, ok)
+	assert.Len(t, refillErr.Mismatches, 1)
+	assert.Equal(t, "Value cannot be fmt.Stringer, but got \"abc\" (from named.json:1:2)", refillErr.Mismatches[0].Error())
+}
+
+func TestRefill_Time(t *testing.T) {
+	type Server struct {
+		Timeout  time.Duration
+		Idle     *time.Duration
+		Deadline time.Time
+		Retry    []time.Duration
+		Invalid  time.Duration
+		Number   time.Duration
+		Expire   time.Time
+	}
+	json := `
+{
+	"Timeout": "1m30s",
+	"Idle": null,
+	"Deadline": "2021-06-01T20:00:00+08:00",
+	"Retry": ["1s", "500ms"],
+	"Invalid": "1 minute",
+	"Number": 30,
+	"Expire": "tomorrow"
+}`
+	idle := time.Minute
+	obj := &Server{Timeout: time.Second, Idle: &idle, Invalid: time.Second, Number: time.Second}
+	root, err := obj2tree.BuildFrom(obj, 1)
+	assert.Nil(t, err)
+	err = json2tree.MergeWithFileName(root, strings.NewReader(json), 2, "server.json")
+	assert.Nil(t, err)
+	err = RefillFrom(tree.ReadFrom(root), obj, 1, &Options{Mode: ModeLenient})
+	refillErr, ok := err.(*RefillError)
+	assert.True(t, ok)
+	assert.Len(t, refillErr.Mismatches, 3)
+	assert.Equal(t, `Invalid cannot be time.Duration, but got "1 minute" (from server.json:7:2): time: unknown unit " minute" in duration "1 minute"`, refillErr.Mismatches[0].Error())
+	assert.Equal(t, "Number cannot be time.Duration, but got 30 (from server.json:8:2)", refillErr.Mismatches[1].Error())
+	assert.Equal(t, `Expire cannot be time.Time, but got "tomorrow" (from server.json:9:2): parsing time "tomorrow" as "2006-01-02T15:04:05.999999999Z07:00": cannot parse "tomorrow" as "2006"`, refillErr.Mismatches[2].Error())
+
+	assert.Equal(t, 90*time.Second, obj.Timeout)
+	assert.Nil(t, obj.Idle)
+	assert.True(t, time.Date(2021, 6, 1, 12, 0, 0, 0, time.UTC).Equal(obj.Deadline))
+	assert.Equal(t, []time.Duration{time.Second, 500 * time.Millisecond}, obj.Retry)
+	assert.Equal(t, time.Second, obj.Invalid)
+	assert.Equal(t, time.Second, obj.Number)
+}
+
+func TestRefill_URL(t *testing.T) {
+	type Proxy struct {
+		Endpoint url.URL
+		Backup   *url.URL
+		Mirrors  []url.URL
+		Invalid  url.URL
+		Number   url.URL
+	}
+	json := `
+{
+	"Backup": "http://backup.example.com:8080",
+	"Mirrors": ["https://a.example.com", "https://b.example.com/path?q=1"],
+	"Invalid": "http://[::1",
+	"Number": 80
+}`
+	obj := &Proxy{Endpoint: url.URL{Scheme: "https", Host: "example.com", Path: "/api"}}
+	root, err := obj2tree.BuildFrom(obj, 1)
+	assert.Nil(t, err)
+	assert.Contains(t, tree2json.DumpToString(root), `"Endpoint": "https://example.com/api"`)
+
+	err = json2tree.MergeWithFileName(root, strings.NewReader(json), 2, "proxy.json")
+	assert.Nil(t, err)
+	walker := tree.ReadFrom(root)
+	assert.True(t, walker.TryEnterObj("Backup"))
+	assert.True(t, CanRefill(walker, reflect.TypeOf(url.URL{}), ModeStrict))
+	walker.Exit()
+	assert.True(t, walker.TryEnterObj("Number"))
+	assert.False(t, CanRefill(walker, reflect.TypeOf(url.URL{}), ModeLenient))
+	walker.Exit()
+
+	err = RefillFrom(tree.ReadFrom(root), obj, 1, &Options{Mode: ModeLenient})
+	refillErr, ok := err.(*RefillError)
+	assert.True(t, ok)
+	assert.Len(t, refillErr.Mismatches, 2)
+	assert.Equal(t, `Invalid cannot be url.URL, but got "http://[::1" (from proxy.json:5:2): parse "http://[::1": missing ']' in host`, refillErr.Mismatches[0].Error())
+	assert.Equal(t, "Number cannot be url.URL, but got 80 (from proxy.json:6:2)", refillErr.Mismatches[1].Error())
+
+	assert.Equal(t, "https://example.com/api", obj.Endpoint.String())
+	assert.Equal(t, "backup.example.com:8080", obj.Backup.Host)
+	assert.Len(t, obj.Mirrors, 2)
+	assert.Equal(t, "q=1", obj.Mirrors[1].RawQuery)
+	assert.Equal(t, url.URL{}, obj.Invalid)
+}
+
+type color int
+
+const (
+	colorRed color = iota
+	colorGreen
+)
+
+func (c color) MarshalText() ([]byte, error) {
+	switch c {
+	case colorRed:
+		return []byte("red"), nil
+	case colorGreen:
+		return []byte("green"), nil
+	}
+	return nil, fmt.Errorf("invalid color %d", int(c))
+}
+
+func (c *color) UnmarshalText(text []byte) error {
+	switch string(text) {
+	case "red":
+		*c = colorRed
+	case "green":
+		*c = colorGreen
+	default:
+		return fmt.Errorf("unknown color %q", string(text))
+	}
+	return nil
+}
+
+func TestRefill_Text(t *testing.T) {
+	type Theme struct {
+		Color   color
+		Palette map[string]color
+		Addr    net.IP
+		Size    *big.Int
+		Invalid color
+	}
+	json := `
+{
+	"Color": "green",
+	"Palette": {"Background": "red"},
+	"Addr": "10.0.0.1",
+	"Size": "123456789012345678901234567890",
+	"Invalid": "blue"
+}`
+	obj := &Theme{Color: colorRed, Addr: net.IPv4(127, 0, 0, 1), Size: big.NewInt(1), Invalid: colorGreen}
+	root, err := obj2tree.BuildFrom(obj, 1)
+	assert.Nil(t, err)
+	walker := tree.ReadFrom(root)
+	assert.True(t, walker.TryEnterObj("Addr"))
+	assert.Equal(t, "127.0.0.1", walker.String())
+	walker.Exit()
+	assert.True(t, walker.TryEnterObj("Palette"))
+	assert.True(t, walker.TryEnterObjPrototype())
+	assert.Equal(t, "red", walker.String())
+	walker.Exit()
+	walker.Exit()
+
+	err = json2tree.MergeWithFileName(root, strings.NewReader(json), 2, "theme.json")
+	assert.Nil(t, err)
+	err = RefillFrom(tree.ReadFrom(root), obj, 1, nil)
+	refillErr, ok := err.(*RefillError)
+	assert.True(t, ok)
+	assert.Len(t, refillErr.Mismatches, 1)
+	assert.Equal(t, "Invalid cannot be tree2obj.color, but got \"blue\" (from theme.json:7:2): unknown color \"blue\"",
+		refillErr.Mismatches[0].Error())
+
+	size, _ := new(big.Int).SetString("123456789012345678901234567890", 10)
+	assert.Equal(t, colorGreen, obj.Color)
+	assert.Equal(t, map[string]color{"Background": colorRed}, obj.Palette)
+	assert.Equal(t, "10.0.0.1", obj.Addr.String())
+	assert.Equal(t, 0, size.Cmp(obj.Size))
+	assert.Equal(t, colorGreen, obj.Invalid)
+
+	_, err = obj2tree.BuildFrom(&Theme{Color: 3}, 1)
+	assert.IsType(t, &obj2tree.MarshalTextError{}, err)
+}
+
+func TestRefill_Array(t *testing.T) {
+	type Host struct {
+		Name string
+		Port int
+	}
+	type Cluster struct {
+		Center   [2]float64
+		Replicas [3]string
+		Hosts    [2]*Host
+		Backup   [2]string
+	}
+	json := `
+{
+	"Center": [1.5, 2.5],
+	"Replicas": ["a", "b"],
+	"Hosts": [{"Name": "primary"}],
+	"Backup": ["x", "y", "z"]
+}`
+	obj := &Cluster{
+		Replicas: [3]string{"r1", "r2", "r3"},
+		Hosts:    [2]*Host{{Name: "h1", Port: 80}, {Name: "h2", Port: 81}},
+	}
+	root, err := obj2tree.BuildFrom(obj, 1)
+	assert.Nil(t, err)
+	err = json2tree.MergeWithFileName(root, strings.NewReader(json), 2, "cluster.json")
+	assert.Nil(t, err)
+	err = RefillFrom(tree.ReadFrom(root), obj, 1, nil)
+	refillErr, ok := err.(*RefillError)
+	assert.True(t, ok)
+	assert.Len(t, refillErr.Mismatches, 1)
+	assert.Equal(t, "Backup cannot be [2]string, but got a list (from cluster.json:6:2): expect at most 2 elements, but got 3",
+		refillErr.Mismatches[0].Error())
+	assert.Equal(t, &Cluster{
+		Center:   [2]float64{1.5, 2.5},
+		Replicas: [3]string{"a", "b", ""},
+		Hosts:    [2]*Host{{Name: "primary", Port: 80}, {Name: "h2", Port: 81}},
+		Backup:   [2]string{"x", "y"},
+	}, obj)
+}
+
+func TestRefill_Unsigned(t *testing.T) {
+	type Limits struct {
+		Max      uint64
+		Port     uint16
+		Level    uint8
+		Offset   int8
+		Negative uint
+		Huge     int64
+		Mask     uint32 `cfgm:"lenient"`
+		Sizes    []uint64
+	}
+	json := `
+{
+	"Max": 18446744073709551615,
+	"Port": 70000,
+	"Level": 200,
+	"Offset": -129,
+	"Negative": -1,
+	"Huge": 9223372036854775808,
+	"Mask": "0xFFFFFFFF",
+	"Sizes": [1, 18446744073709551614]
+}`
+	obj := &Limits{Max: 1, Port: 80, Offset: 1, Negative: 1, Huge: 1}
+	root, err := obj2tree.BuildFrom(obj, 1)
+	assert.Nil(t, err)
+	err = json2tree.MergeWithFileName(root, strings.NewReader(json), 2, "limits.json")
+	assert.Nil(t, err)
+	err = RefillFrom(tree.ReadFrom(root), obj, 1, nil)
+	refillErr, ok := err.(*RefillError)
+	assert.True(t, ok)
+	messages := make([]string, 0)
+	for _, mismatch := range refillErr.Mismatches {
+		messages = append(messages, mismatch.Error())
+	}
+	assert.Equal(t, []string{
+		"Port cannot be uint16, but got 70000 (from limits.json:4:2): out of the range [0, 65535]",
+		"Offset cannot be int8, but got -129 (from limits.json:6:2): out of the range [-128, 127]",
+		"Negative cannot be uint, but got -1 (from limits.json:7:2): out of the range [0, 18446744073709551615]",
+		"Huge cannot be int64, but got 9223372036854775808 (from limits.json:8:2): out of the range [-9223372036854775808, 9223372036854775807]",
+	}, messages)
+	assert.Equal(t, &Limits{
+		Max:      math.MaxUint64,
+		Port:     80,
+		Level:    200,
+		Offset:   1,
+		Negative: 1,
+		Huge:     1,
+		Mask:     math.MaxUint32,
+		Sizes:    []uint64{1, math.MaxUint64 - 1},
+	}, obj)
+
+	root, err = obj2tree.BuildFrom(&Limits{Max: math.MaxUint64}, 1)
+	assert.Nil(t, err)
+	walker := tree.ReadFrom(root)
+	assert.True(t, walker.TryEnterObj("Max"))
+	assert.False(t, walker.Has(tree.NodeKeyInt))
+	assert.Equal(t, uint64(math.MaxUint64), walker.Uint())
+}
+
+func TestRefill_Embedded(t *testing.T) {
+	type Person struct {
+		Name string
+		Age  int
+	}
+	type Contact struct {
+		Email string
+	}
+	type Parent struct {
+		Person
+		*Contact
+		Age uint8
+	}
+	json := `
+{
+	"Name": "Father",
+	"Age": 41,
+	"Email": "father@example.com"
+}`
+	obj := &Parent{Person: Person{Name: "Default", Age: 1}, Age: 40}
+	root, err := obj2tree.BuildFrom(obj, 1)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"Age", "Email", "Name"}, sortedKeys(tree.ReadFrom(root)))
+
+	err = json2tree.Merge(root, strings.NewReader(json), 2)
+	assert.Nil(t, err)
+	err = RefillFrom(tree.ReadFrom(root), obj, 1, nil)
+	assert.Nil(t, err)
+	assert.Equal(t, &Parent{
+		Person:  Person{Name: "Father", Age: 1},
+		Contact: &Contact{Email: "father@example.com"},
+		Age:     41,
+	}, obj)
+
+	obj = &Parent{}
+	root, err = obj2tree.BuildFrom(obj, 1)
+	assert.Nil(t, err)
+	err = json2tree.Merge(root, strings.NewReader(`{"Name": "Father"}`), 2)
+	assert.Nil(t, err)
+	err = RefillFrom(tree.ReadFrom(root), obj, 1, nil)
+	assert.Nil(t, err)
+	assert.Nil(t, obj.Contact)
+	assert.Equal(t, "Father", obj.Name)
+}
+
+func sortedKeys(walker tree.ReadonlyWalker) []string {
+	keys := walker.ObjKeys()
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,54 @@
+package validate
+
+import (
+	"fmt"
+	"strings"
+)
+
+/*
+FieldError describes a field which breaks a rule, Source is where the value comes from, e.g.
+"config.json:3:5", "$APP_DB_PORT" or "-Ddb.Port=0", and it is empty for the default value.
+*/
+type FieldError struct {
+	Path    string
+	Value   string
+	Source  string
+	Rule    string
+	Message string
+}
+
+func (err *FieldError) Error() string {
+	source := err.Source
+	if len(source) == 0 {
+		source = "default"
+	}
+	return fmt.Sprintf("%s %s, but got %s (from %s)", err.Path, err.Message, err.Value, source)
+}
+
+type ValidationError struct {
+	Fields []*FieldError
+}
+
+func (err *ValidationError) Error() string {
+	builder := strings.Builder{}
+	builder.WriteString(fmt.Sprintf("cfgm validation error: %d field(s) are invalid", len(err.Fields)))
+	for _, field := range err.Fields {
+		builder.WriteString("\n\t")
+		builder.WriteString(field.Error())
+	}
+	return builder.String()
+}
+
+type InvalidRuleError struct {
+	Type  string
+	Field string
+	Rule  string
+	Inner error
+}
+
+func (err *InvalidRuleError) Error() string {
+	if err.Inner != nil {
+		return fmt.Sprintf("invalid rule (%s) of field %s.%s: %s", err.Rule, err.Type, err.Field, err.Inner.Error())
+	}
+	return fmt.Sprintf("invalid rule (%s) of field %s.%s", err.Rule, err.Type, err.Field)
+}
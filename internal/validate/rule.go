@@ -0,0 +1,201 @@
+package validate
+
+import (
+	"errors"
+	"fmt"
+	"github.com/SnowPhoenix0105/cfgm/internal/fields"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	ruleRequired = fields.RuleRequired
+	ruleMin      = "min"
+	ruleMax      = "max"
+	ruleLen      = "len"
+	ruleOneOf    = "oneof"
+	rulePattern  = "pattern"
+)
+
+type rule struct {
+	name    string
+	text    string
+	number  float64
+	options []string
+	pattern *regexp.Regexp
+}
+
+/*
+parseRules parses the rules in a tag like `cfgm:"required,min=1,max=10"`. Because a pattern may
+contain ',', the "pattern=..." rule must be the last one, and the rest of the tag is the pattern.
+The name of the field in the tag must be removed before, see fields.ParseTag().
+
+Supported:
+
+	required: the value must not be null, zero or empty
+	min=N, max=N: the bounds of a number, or the bounds of the length of a string, slice or map
+	len=N: the length of a string, slice or map
+	oneof=a|b|c: the value must be one of the options
+	pattern=REGEXP: a string must match the regular expression
+*/
+func parseRules(tag string) ([]*rule, error) {
+	rules := make([]*rule, 0)
+	for len(tag) != 0 {
+		var text string
+		if strings.HasPrefix(tag, rulePattern+"=") {
+			text, tag = tag, ""
+		} else if index := strings.IndexByte(tag, ','); index >= 0 {
+			text, tag = tag[:index], tag[index+1:]
+		} else {
+			text, tag = tag, ""
+		}
+		text = strings.TrimSpace(text)
+		if len(text) == 0 || fields.IsOption(text) {
+			continue
+		}
+		r, err := parseRule(text)
+		if err != nil {
+			return nil, err
+		}
+		rules = append(rules, r)
+	}
+	return rules, nil
+}
+
+func parseRule(text string) (*rule, error) {
+	name, param := text, ""
+	if index := strings.IndexByte(text, '='); index >= 0 {
+		name, param = text[:index], text[index+1:]
+	}
+	r := &rule{name: name, text: text}
+	switch name {
+	case ruleRequired:
+		if len(param) != 0 {
+			return nil, errors.New("unexpected parameter")
+		}
+	case ruleMin, ruleMax, ruleLen:
+		number, err := strconv.ParseFloat(param, 64)
+		if err != nil {
+			return nil, err
+		}
+		r.number = number
+	case ruleOneOf:
+		if len(param) == 0 {
+			return nil, errors.New("no option")
+		}
+		r.options = strings.Split(param, "|")
+	case rulePattern:
+		pattern, err := regexp.Compile(param)
+		if err != nil {
+			return nil, err
+		}
+		r.pattern = pattern
+	default:
+		return nil, errors.New("unknown rule")
+	}
+	return r, nil
+}
+
+func isEmpty(value reflect.Value) bool {
+	if !value.IsValid() {
+		return true
+	}
+	switch value.Kind() {
+	case reflect.String, reflect.Slice, reflect.Map, reflect.Array:
+		return value.Len() == 0
+	case reflect.Struct:
+		return false
+	}
+	return value.IsZero()
+}
+
+func lengthOf(value reflect.Value) (int, bool) {
+	switch value.Kind() {
+	case reflect.String:
+		return utf8.RuneCountInString(value.String()), true
+	case reflect.Slice, reflect.Map, reflect.Array:
+		return value.Len(), true
+	}
+	return 0, false
+}
+
+func numberOf(value reflect.Value) (float64, bool) {
+	switch value.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(value.Int()), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(value.Uint()), true
+	case reflect.Float32, reflect.Float64:
+		return value.Float(), true
+	}
+	return 0, false
+}
+
+func formatNumber(number float64) string {
+	return strconv.FormatFloat(number, 'f', -1, 64)
+}
+
+/*
+check returns the message when value breaks the rule, or an empty string when it does not.
+The value is invalid when it is a nil pointer, and only "required" is checked in this case.
+*/
+func (r *rule) check(value reflect.Value) (string, error) {
+	if r.name == ruleRequired {
+		if isEmpty(value) {
+			return "is required", nil
+		}
+		return "", nil
+	}
+	if !value.IsValid() {
+		return "", nil
+	}
+	switch r.name {
+	case ruleMin, ruleMax:
+		if number, ok := numberOf(value); ok {
+			if r.name == ruleMin && number < r.number {
+				return "must be at least " + formatNumber(r.number), nil
+			}
+			if r.name == ruleMax && number > r.number {
+				return "must be at most " + formatNumber(r.number), nil
+			}
+			return "", nil
+		}
+		if length, ok := lengthOf(value); ok {
+			if r.name == ruleMin && float64(length) < r.number {
+				return "must have a length of at least " + formatNumber(r.number), nil
+			}
+			if r.name == ruleMax && float64(length) > r.number {
+				return "must have a length of at most " + formatNumber(r.number), nil
+			}
+			return "", nil
+		}
+	case ruleLen:
+		if length, ok := lengthOf(value); ok {
+			if float64(length) != r.number {
+				return "must have a length of " + formatNumber(r.number), nil
+			}
+			return "", nil
+		}
+	case ruleOneOf:
+		if _, ok := numberOf(value); ok || value.Kind() == reflect.String || value.Kind() == reflect.Bool {
+			str := fmt.Sprint(value.Interface())
+			for _, option := range r.options {
+				if str == option {
+					return "", nil
+				}
+			}
+			return "must be one of " + strings.Join(r.options, "|"), nil
+		}
+	case rulePattern:
+		if value.Kind() == reflect.String {
+			if !r.pattern.MatchString(value.String()) {
+				return "must match pattern " + r.pattern.String(), nil
+			}
+			return "", nil
+		}
+	}
+	return "", fmt.Errorf("cannot be applied to %s", value.Type().String())
+}
@@ -0,0 +1,200 @@
+package validate
+
+import (
+	"fmt"
+	"github.com/SnowPhoenix0105/cfgm/internal/fields"
+	"github.com/SnowPhoenix0105/cfgm/internal/tree"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const ruleTag = "cfgm"
+
+/*
+Validate checks the fields of the object pointed by obj with the rules in their "cfgm" tags, and
+the nested structs in fields, slices and maps are checked recursively. The node is the tree where
+obj was refilled from, which provides the sources of values, and it can be nil. The path is the
+prefix of the paths in errors, and naming converts the names of fields to the keys in the tree and
+the paths, see fields.Of().
+
+Errors:
+
+	InvalidRuleError: a tag cannot be parsed, or a rule cannot be applied to the type of field
+	ValidationError: some fields break their rules, all of them are listed
+*/
+func Validate(obj interface{}, node *tree.Node, path []string, naming fields.Naming) error {
+	env := validateEnv{
+		naming: naming,
+		path:   append(make([]string, 0, len(path)), path...),
+		fields: make([]*FieldError, 0),
+	}
+	err := env.validate(reflect.ValueOf(obj), node)
+	if err != nil {
+		return err
+	}
+	if len(env.fields) != 0 {
+		return &ValidationError{Fields: env.fields}
+	}
+	return nil
+}
+
+type validateEnv struct {
+	naming fields.Naming
+	path   []string
+	fields []*FieldError
+}
+
+func indirect(value reflect.Value) reflect.Value {
+	for value.Kind() == reflect.Ptr {
+		if value.IsNil() {
+			return reflect.Value{}
+		}
+		value = value.Elem()
+	}
+	return value
+}
+
+func objChild(node *tree.Node, key string) *tree.Node {
+	if node == nil || !node.Has(tree.NodeKeyObj) {
+		return nil
+	}
+	return node.Obj()[key]
+}
+
+func listChild(node *tree.Node, index int) *tree.Node {
+	if node == nil || !node.Has(tree.NodeKeyList) {
+		return nil
+	}
+	list := node.List()
+	if index >= len(list) {
+		return nil
+	}
+	return list[index]
+}
+
+func (env *validateEnv) enter(name string) {
+	env.path = append(env.path, name)
+}
+
+func (env *validateEnv) exit() {
+	env.path = env.path[:len(env.path)-1]
+}
+
+func (env *validateEnv) validate(value reflect.Value, node *tree.Node) error {
+	value = indirect(value)
+	if !value.IsValid() {
+		return nil
+	}
+	switch value.Kind() {
+	case reflect.Struct:
+		return env.validateStruct(value, node)
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < value.Len(); i++ {
+			env.enter("[" + strconv.Itoa(i) + "]")
+			err := env.validate(value.Index(i), listChild(node, i))
+			env.exit()
+			if err != nil {
+				return err
+			}
+		}
+	case reflect.Map:
+		if value.Type().Key().Kind() != reflect.String {
+			return nil
+		}
+		keys := value.MapKeys()
+		sort.Slice(keys, func(i, j int) bool {
+			return keys[i].String() < keys[j].String()
+		})
+		for _, key := range keys {
+			env.enter(key.String())
+			err := env.validate(value.MapIndex(key), objChild(node, key.String()))
+			env.exit()
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (env *validateEnv) validateStruct(value reflect.Value, node *tree.Node) error {
+	typ := value.Type()
+	for _, f := range fields.Of(typ, env.naming) {
+		field := f.StructField
+		if len(field.PkgPath) != 0 {
+			// unexported
+			continue
+		}
+		fieldValue, ok := fields.ByIndex(value, f.Index, false)
+		if !ok {
+			// promoted from a nil embedded pointer
+			fieldValue = reflect.Zero(field.Type)
+		}
+		child := objChild(node, f.Name)
+		env.enter(f.Name)
+		err := env.validateField(fieldValue, field, typ, child)
+		if err == nil {
+			err = env.validate(fieldValue, child)
+		}
+		env.exit()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (env *validateEnv) validateField(value reflect.Value, field reflect.StructField, typ reflect.Type, node *tree.Node) error {
+	tag, ok := field.Tag.Lookup(ruleTag)
+	if !ok {
+		return nil
+	}
+	_, tag = fields.ParseTag(tag)
+	rules, err := parseRules(tag)
+	if err != nil {
+		return &InvalidRuleError{Type: typ.String(), Field: field.Name, Rule: tag, Inner: err}
+	}
+	value = indirect(value)
+	for _, r := range rules {
+		message, err := r.check(value)
+		if err != nil {
+			return &InvalidRuleError{Type: typ.String(), Field: field.Name, Rule: r.text, Inner: err}
+		}
+		if len(message) == 0 {
+			continue
+		}
+		source := ""
+		if node != nil {
+			source = node.Source()
+		}
+		formatted := tree.Redacted
+		if (node == nil || !node.Secret()) && field.Tag.Get(fields.SecretTag) != "true" {
+			formatted = formatValue(value)
+		}
+		env.fields = append(env.fields, &FieldError{
+			Path:    strings.Join(env.path, "."),
+			Value:   formatted,
+			Source:  source,
+			Rule:    r.text,
+			Message: message,
+		})
+	}
+	return nil
+}
+
+func formatValue(value reflect.Value) string {
+	if !value.IsValid() {
+		return "null"
+	}
+	switch value.Kind() {
+	case reflect.String:
+		return strconv.Quote(value.String())
+	case reflect.Slice, reflect.Map, reflect.Array:
+		return fmt.Sprintf("%d element(s)", value.Len())
+	case reflect.Struct:
+		return value.Type().String()
+	}
+	return fmt.Sprint(value.Interface())
+}
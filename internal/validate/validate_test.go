@@ -0,0 +1,113 @@
+package validate
+
+import (
+	"github.com/SnowPhoenix0105/cfgm/internal/fields"
+	"github.com/SnowPhoenix0105/cfgm/internal/json2tree"
+	"github.com/SnowPhoenix0105/cfgm/internal/obj2tree"
+	"github.com/SnowPhoenix0105/cfgm/internal/tree2obj"
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+)
+
+func TestValidate(t *testing.T) {
+	type Route struct {
+		Path string `cfgm:"required,pattern=^/[a-z,/]*$"`
+	}
+	type Server struct {
+		Host    string   `cfgm:"required"`
+		Port    int      `cfgm:"min=1,max=65535"`
+		Mode    string   `cfgm:"oneof=debug|release"`
+		Level   *int     `cfgm:"oneof=1|2|3"`
+		Key     string   `cfgm:"len=4"`
+		Tags    []string `cfgm:"min=1, max=2"`
+		Timeout *float64 `cfgm:"required"`
+		Routes  []Route
+		Named   map[string]*Route
+	}
+	timeout := 1.5
+	obj := Server{
+		Timeout: &timeout,
+		Host:    "localhost",
+		Port:    80,
+		Mode:    "debug",
+		Key:     "abcd",
+		Tags:    []string{"a"},
+		Routes: []Route{
+			{Path: "/"},
+		},
+		Named: map[string]*Route{},
+	}
+	root, err := obj2tree.BuildFrom(&obj, 1)
+	assert.Nil(t, err)
+	assert.Nil(t, Validate(&obj, root, []string{"server"}, fields.NamingExact))
+
+	err = json2tree.MergeWithFileName(root, stringReader(`{
+	"Host": "",
+	"Port": 0,
+	"Mode": "test",
+	"Key": "abcde",
+	"Tags": ["a", "b", "c"],
+	"Timeout": null,
+	"Routes": [{"Path": "/a,b"}, {"Path": "a"}],
+	"Named": {"x": {"Path": ""}}
+}`), 2, "config.json")
+	assert.Nil(t, err)
+	tree2obj.Refill(root, &obj, 1, 3)
+
+	err = Validate(&obj, root, []string{"server"}, fields.NamingExact)
+	assert.Equal(t, &ValidationError{Fields: []*FieldError{
+		{Path: "server.Host", Value: `""`, Source: "config.json:2:2", Rule: "required", Message: "is required"},
+		{Path: "server.Port", Value: "0", Source: "config.json:3:2", Rule: "min=1", Message: "must be at least 1"},
+		{Path: "server.Mode", Value: `"test"`, Source: "config.json:4:2", Rule: "oneof=debug|release", Message: "must be one of debug|release"},
+		{Path: "server.Key", Value: `"abcde"`, Source: "config.json:5:2", Rule: "len=4", Message: "must have a length of 4"},
+		{Path: "server.Tags", Value: "3 element(s)", Source: "config.json:6:2", Rule: "max=2", Message: "must have a length of at most 2"},
+		{Path: "server.Timeout", Value: "null", Source: "config.json:7:2", Rule: "required", Message: "is required"},
+		{Path: "server.Routes.[1].Path", Value: `"a"`, Source: "config.json:8:32", Rule: "pattern=^/[a-z,/]*$", Message: "must match pattern ^/[a-z,/]*$"},
+		{Path: "server.Named.x.Path", Value: `""`, Source: "config.json:9:18", Rule: "required", Message: "is required"},
+		{Path: "server.Named.x.Path", Value: `""`, Source: "config.json:9:18", Rule: "pattern=^/[a-z,/]*$", Message: "must match pattern ^/[a-z,/]*$"},
+	}}, err)
+	assert.Contains(t, err.Error(), "server.Timeout is required, but got null (from config.json:7:2)")
+
+	field := FieldError{Path: "a", Value: "0", Message: "must be at least 1"}
+	assert.Equal(t, "a must be at least 1, but got 0 (from default)", field.Error())
+}
+
+func TestValidate_Embedded(t *testing.T) {
+	type Endpoint struct {
+		Host string `cfgm:"required"`
+	}
+	type Server struct {
+		Endpoint
+		Port int `cfgm:"min=1"`
+	}
+	obj := Server{Port: 80}
+	root, err := obj2tree.BuildFrom(&obj, 1)
+	assert.Nil(t, err)
+	err = Validate(&obj, root, []string{"server"}, fields.NamingExact)
+	assert.Equal(t, &ValidationError{Fields: []*FieldError{
+		{Path: "server.Host", Value: `""`, Rule: "required", Message: "is required"},
+	}}, err)
+}
+
+func TestValidate_InvalidRule(t *testing.T) {
+	type Unknown struct {
+		Value int `cfgm:",positive"`
+	}
+	type BadNumber struct {
+		Value int `cfgm:"min=one"`
+	}
+	type BadType struct {
+		Value bool `cfgm:"min=1"`
+	}
+	type BadPattern struct {
+		Value string `cfgm:"pattern=("`
+	}
+	for _, obj := range []interface{}{&Unknown{}, &BadNumber{}, &BadType{}, &BadPattern{}} {
+		assert.IsType(t, &InvalidRuleError{}, Validate(obj, nil, nil, fields.NamingExact))
+	}
+}
+
+func stringReader(str string) *strings.Reader {
+	return strings.NewReader(str)
+}
@@ -0,0 +1,50 @@
+package yaml2tree
+
+import "fmt"
+
+type IOError struct {
+	Inner error
+}
+
+func (err *IOError) Error() string {
+	return fmt.Sprintf("unexpect IO error, may be caused by: %s", err.Inner.Error())
+}
+
+type SyntaxError struct {
+	Line    int
+	Row     int
+	Message string
+}
+
+func (err *SyntaxError) Error() string {
+	return fmt.Sprintf("syntax error at line %d, row %d: %s", err.Line, err.Row, err.Message)
+}
+
+type UndefinedAliasError struct {
+	Line  int
+	Row   int
+	Alias string
+}
+
+func (err *UndefinedAliasError) Error() string {
+	return fmt.Sprintf("undefined alias (*%s) at line %d, row %d", err.Alias, err.Line, err.Row)
+}
+
+type DuplicateKeyError struct {
+	Line int
+	Row  int
+	Key  string
+}
+
+func (err *DuplicateKeyError) Error() string {
+	return fmt.Sprintf("duplicate key (%s) at line %d, row %d", err.Key, err.Line, err.Row)
+}
+
+type InvalidKeyError struct {
+	Line int
+	Row  int
+}
+
+func (err *InvalidKeyError) Error() string {
+	return fmt.Sprintf("key of mapping at line %d, row %d is not a scalar", err.Line, err.Row)
+}
@@ -0,0 +1,205 @@
+package yaml2tree
+
+import (
+	"github.com/SnowPhoenix0105/cfgm/internal/tree"
+	"io"
+	"strings"
+)
+
+func MergeString(root *tree.Node, yaml string, time tree.ModifyTime) error {
+	reader := strings.NewReader(yaml)
+	return Merge(root, reader, time)
+}
+
+/*
+Merge parses the YAML document from reader, and merges it into root with the same rules of
+json2tree.Merge.
+
+Errors:
+
+	IOError: fail to read from reader
+	SyntaxError: the document is not a valid YAML document
+	UndefinedAliasError: an alias refers to an anchor which is not defined before it
+	DuplicateKeyError: a mapping contains the same key twice
+	InvalidKeyError: a mapping key is not a scalar
+*/
+func Merge(root *tree.Node, reader io.RuneReader, time tree.ModifyTime) error {
+	return MergeWithFileName(root, reader, time, "")
+}
+
+/*
+MergeWithFileName works like Merge, and records the position of each key and item in the file
+as the source of its node, e.g. "config.yaml:3:5".
+*/
+func MergeWithFileName(root *tree.Node, reader io.RuneReader, time tree.ModifyTime, fileName string) error {
+	src, err := readAll(reader)
+	if err != nil {
+		return err
+	}
+	par := newParser(src)
+	doc, err := par.parseDocument()
+	if err != nil {
+		return err
+	}
+	env := mergeEnv{
+		walker:   tree.WriteFrom(root, time),
+		fileName: fileName,
+	}
+	return env.merge(doc)
+}
+
+func readAll(reader io.RuneReader) ([]rune, error) {
+	src := make([]rune, 0)
+	lastCR := false
+	for {
+		char, _, err := reader.ReadRune()
+		if err == io.EOF {
+			return src, nil
+		}
+		if err != nil {
+			return nil, &IOError{Inner: err}
+		}
+		// normalize "\r\n" and "\r" to "\n"
+		if char == '\n' && lastCR {
+			lastCR = false
+			continue
+		}
+		lastCR = char == '\r'
+		if lastCR {
+			char = '\n'
+		}
+		src = append(src, char)
+	}
+}
+
+type mergeEnv struct {
+	walker   tree.Walker
+	fileName string
+}
+
+func (env *mergeEnv) merge(node *yamlNode) error {
+	node = node.resolve()
+	switch node.kind {
+	case kindMapping:
+		tree.KeepValues(env.walker, tree.NodeKeyObj)
+		return env.mergeMapping(node)
+	case kindSequence:
+		tree.KeepValues(env.walker, tree.NodeKeyList)
+		return env.mergeSequence(node)
+	default:
+		return env.mergeScalar(node)
+	}
+}
+
+func (env *mergeEnv) mergeScalar(node *yamlNode) error {
+	switch value := resolveScalar(node).(type) {
+	case nil:
+		tree.SetNull(env.walker)
+	case int64:
+		tree.KeepValues(env.walker, tree.NodeKeyInt, tree.NodeKeyFloat)
+		env.walker.SetInt(value)
+		env.walker.SetNullFor(tree.NodeKeyInt, false)
+		env.walker.SetFloat(float64(value))
+		env.walker.SetNullFor(tree.NodeKeyFloat, false)
+	case uint64:
+		tree.KeepValues(env.walker, tree.NodeKeyUint, tree.NodeKeyFloat)
+		env.walker.SetUint(value)
+		env.walker.SetNullFor(tree.NodeKeyUint, false)
+		env.walker.SetFloat(float64(value))
+		env.walker.SetNullFor(tree.NodeKeyFloat, false)
+	case float64:
+		tree.KeepValues(env.walker, tree.NodeKeyFloat)
+		env.walker.SetFloat(value)
+		env.walker.SetNullFor(tree.NodeKeyFloat, false)
+	case bool:
+		tree.KeepValues(env.walker, tree.NodeKeyBool)
+		env.walker.SetBool(value)
+		env.walker.SetNullFor(tree.NodeKeyBool, false)
+	case string:
+		tree.KeepValues(env.walker, tree.NodeKeyString)
+		env.walker.SetString(value)
+		env.walker.SetNullFor(tree.NodeKeyString, false)
+	}
+	return nil
+}
+
+/*
+mergeMapping merges the mappings referred by merge keys ("<<") first, so that the keys of the
+mapping itself always override the merged ones.
+*/
+func (env *mergeEnv) mergeMapping(node *yamlNode) error {
+	for _, pair := range node.pairs {
+		if !pair.key.isMergeKey() {
+			continue
+		}
+		value := pair.value.resolve()
+		if value.kind == kindSequence {
+			// the former mappings in the sequence take precedence
+			for i := len(value.items) - 1; i >= 0; i-- {
+				if err := env.mergeMergedMapping(value.items[i]); err != nil {
+					return err
+				}
+			}
+			continue
+		}
+		if err := env.mergeMergedMapping(value); err != nil {
+			return err
+		}
+	}
+
+	keySet := make(map[string]struct{})
+	for _, pair := range node.pairs {
+		if pair.key.isMergeKey() {
+			continue
+		}
+		key := pair.key.resolve()
+		if key.kind != kindScalar {
+			return &InvalidKeyError{
+				Line: pair.key.line,
+				Row:  pair.key.row,
+			}
+		}
+		if _, ok := keySet[key.value]; ok {
+			return &DuplicateKeyError{
+				Line: pair.key.line,
+				Row:  pair.key.row,
+				Key:  key.value,
+			}
+		}
+		keySet[key.value] = struct{}{}
+
+		env.walker.EnterObj(key.value)
+		env.walker.SetSource(tree.FilePosition(env.fileName, pair.key.line, pair.key.row))
+		err := env.merge(pair.value)
+		env.walker.Exit()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (env *mergeEnv) mergeMergedMapping(node *yamlNode) error {
+	resolved := node.resolve()
+	if resolved.kind != kindMapping {
+		return &SyntaxError{
+			Line:    node.line,
+			Row:     node.row,
+			Message: "the value of merge key must be a mapping or a sequence of mappings",
+		}
+	}
+	return env.mergeMapping(resolved)
+}
+
+func (env *mergeEnv) mergeSequence(node *yamlNode) error {
+	for i, item := range node.items {
+		env.walker.EnterList(i)
+		env.walker.SetSource(tree.FilePosition(env.fileName, item.line, item.row))
+		err := env.merge(item)
+		env.walker.Exit()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
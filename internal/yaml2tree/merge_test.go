@@ -0,0 +1,238 @@
+package yaml2tree
+
+import (
+	"github.com/SnowPhoenix0105/cfgm/internal/obj2tree"
+	"github.com/SnowPhoenix0105/cfgm/internal/tree"
+	"github.com/SnowPhoenix0105/cfgm/internal/tree2obj"
+	"github.com/stretchr/testify/assert"
+	"strconv"
+	"testing"
+)
+
+func enter(t *testing.T, root *tree.Node, path ...string) tree.ReadonlyWalker {
+	walker := tree.ReadFrom(root)
+	for _, p := range path {
+		if index, err := strconv.Atoi(p); err == nil {
+			assert.True(t, walker.TryEnterList(index), p)
+		} else {
+			assert.True(t, walker.TryEnterObj(p), p)
+		}
+	}
+	return walker
+}
+
+func TestMerge_Block(t *testing.T) {
+	yaml := `
+%YAML 1.2
+---
+# comment
+server:
+  host: localhost   # trailing comment
+  port: 8080
+  ratio: 0.5
+  debug: true
+  empty:
+  quoted: "a\tb\u0041"
+  single: 'it''s'
+  hex: 0x1F
+list:
+- a
+- b
+nested:
+  - name: x
+    value: 1
+  - - 1
+    - 2
+...
+`
+	root := tree.NewNode()
+	err := MergeString(root, yaml, 1)
+	assert.Nil(t, err)
+
+	assert.Equal(t, "localhost", enter(t, root, "server", "host").String())
+	assert.Equal(t, int64(8080), enter(t, root, "server", "port").Int())
+	assert.Equal(t, float64(8080), enter(t, root, "server", "port").Float())
+	assert.Equal(t, 0.5, enter(t, root, "server", "ratio").Float())
+	assert.True(t, enter(t, root, "server", "debug").Bool())
+	assert.False(t, enter(t, root, "server", "empty").Has(tree.NodeKeyString))
+	assert.Equal(t, "a\tbA", enter(t, root, "server", "quoted").String())
+	assert.Equal(t, "it's", enter(t, root, "server", "single").String())
+	assert.Equal(t, int64(31), enter(t, root, "server", "hex").Int())
+	assert.Equal(t, 2, enter(t, root, "list").ListLen())
+	assert.Equal(t, "b", enter(t, root, "list", "1").String())
+	assert.Equal(t, "x", enter(t, root, "nested", "0", "name").String())
+	assert.Equal(t, int64(1), enter(t, root, "nested", "0", "value").Int())
+	assert.Equal(t, int64(2), enter(t, root, "nested", "1", "1").Int())
+}
+
+func TestMerge_Flow(t *testing.T) {
+	yaml := `{a: [1, 2, {b: c}], "d": {e: f, g: }, h: [x: y], url: http://localhost:80}`
+	root := tree.NewNode()
+	err := MergeString(root, yaml, 1)
+	assert.Nil(t, err)
+
+	assert.Equal(t, 3, enter(t, root, "a").ListLen())
+	assert.Equal(t, "c", enter(t, root, "a", "2", "b").String())
+	assert.Equal(t, "f", enter(t, root, "d", "e").String())
+	assert.Equal(t, "y", enter(t, root, "h", "0", "x").String())
+	assert.Equal(t, "http://localhost:80", enter(t, root, "url").String())
+}
+
+func TestMerge_MultiLineScalars(t *testing.T) {
+	yaml := `
+literal: |
+  line1
+    indented
+
+  line3
+folded: >-
+  folded
+  text
+
+  new paragraph
+keep: |+
+  keep
+
+strip: |-
+  strip
+plain: first
+  second
+
+  third
+quoted: "first
+  second"
+last: end
+`
+	root := tree.NewNode()
+	err := MergeString(root, yaml, 1)
+	assert.Nil(t, err)
+
+	assert.Equal(t, "line1\n  indented\n\nline3\n", enter(t, root, "literal").String())
+	assert.Equal(t, "folded text\nnew paragraph", enter(t, root, "folded").String())
+	assert.Equal(t, "keep\n\n", enter(t, root, "keep").String())
+	assert.Equal(t, "strip", enter(t, root, "strip").String())
+	assert.Equal(t, "first second\nthird", enter(t, root, "plain").String())
+	assert.Equal(t, "first second", enter(t, root, "quoted").String())
+	assert.Equal(t, "end", enter(t, root, "last").String())
+}
+
+func TestMerge_AnchorAndAlias(t *testing.T) {
+	yaml := `
+base: &base
+  host: localhost
+  port: 80
+dev:
+  <<: *base
+  port: 8080
+hosts:
+  - &h a
+  - *h
+`
+	root := tree.NewNode()
+	err := MergeString(root, yaml, 1)
+	assert.Nil(t, err)
+
+	assert.Equal(t, "localhost", enter(t, root, "dev", "host").String())
+	assert.Equal(t, int64(8080), enter(t, root, "dev", "port").Int())
+	assert.Equal(t, int64(80), enter(t, root, "base", "port").Int())
+	assert.Equal(t, "a", enter(t, root, "hosts", "1").String())
+}
+
+func TestMerge_Errors(t *testing.T) {
+	cases := map[string]interface{}{
+		"a: *undefined":         &UndefinedAliasError{},
+		"a: 1\na: 2":            &DuplicateKeyError{},
+		"a:\n  b: 1\n   c: 2":   &SyntaxError{},
+		"a: [1, 2":              &SyntaxError{},
+		"a: \"unterminated":     &SyntaxError{},
+		"a: 1\n---\nb: 2":       &SyntaxError{},
+		"- a\nb: 1":             &SyntaxError{},
+		"a: {[1]: 2}":           &InvalidKeyError{},
+		"a:\n  - 1\n  b: 2\n":   &SyntaxError{},
+		"a: \"bad \\q escape\"": &SyntaxError{},
+		"a: b: c":               &SyntaxError{},
+	}
+	for yaml, expect := range cases {
+		err := MergeString(tree.NewNode(), yaml, 1)
+		assert.IsType(t, expect, err, yaml)
+	}
+
+	err := MergeString(tree.NewNode(), "a:\n  b: 1\n  c: [1,\n   2, }", 1)
+	assert.Equal(t, &SyntaxError{Line: 4, Row: 7, Message: "unexpected character '}'"}, err)
+}
+
+func TestMerge_TabIndentation(t *testing.T) {
+	cases := map[string]*SyntaxError{
+		"server:\n\tport: 80":             {Line: 2, Row: 1, Message: "tab cannot be used for indentation"},
+		"server:\n  host: a\n \tport: 80": {Line: 3, Row: 2, Message: "tab cannot be used for indentation"},
+		"- name: a\n\tport: 80":           {Line: 2, Row: 1, Message: "tab cannot be used for indentation"},
+		"server: &s\n\tport: 80":          {Line: 2, Row: 1, Message: "tab cannot be used for indentation"},
+	}
+	for yaml, expect := range cases {
+		err := MergeString(tree.NewNode(), yaml, 1)
+		assert.Equal(t, expect, err, yaml)
+	}
+
+	// tabs are allowed as separation and in blank lines
+	root := tree.NewNode()
+	err := MergeString(root, "server:\n  port:\t80\t# comment\n\t\n  host: a\n", 1)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(80), enter(t, root, "server", "port").Int())
+	assert.Equal(t, "a", enter(t, root, "server", "host").String())
+}
+
+func TestMerge_Refill(t *testing.T) {
+	type Tuple struct {
+		A int
+		B int
+	}
+	type Class struct {
+		Name     *string
+		CoverMap map[string]Tuple
+		ProtoMap map[string]*Tuple
+		List     []*Tuple
+	}
+	name := "name"
+	obj := Class{
+		Name: &name,
+		CoverMap: map[string]Tuple{
+			"Key1": {A: 1, B: 2},
+		},
+		ProtoMap: map[string]*Tuple{
+			"__prototype__": {A: 3, B: 4},
+			"Key1":          {A: 1, B: 2},
+		},
+		List: []*Tuple{{A: 1, B: 2}},
+	}
+	yaml := `
+Name: null
+CoverMap:
+  Key2: {A: 6}
+ProtoMap:
+  Key1:
+    A: 5
+  Key2:
+    A: 6
+List:
+  - A: 5
+  - A: 6
+`
+	root, err := obj2tree.BuildFrom(&obj, 1)
+	assert.Nil(t, err)
+	err = MergeString(root, yaml, 2)
+	assert.Nil(t, err)
+	tree2obj.Refill(root, &obj, 1, 3)
+
+	expect := Class{
+		Name: nil,
+		CoverMap: map[string]Tuple{
+			"Key2": {A: 6, B: 0},
+		},
+		ProtoMap: map[string]*Tuple{
+			"Key1": {A: 5, B: 2},
+			"Key2": {A: 6, B: 4},
+		},
+		List: []*Tuple{{A: 5, B: 2}, {A: 6, B: 0}},
+	}
+	assert.Equal(t, expect, obj)
+}
@@ -0,0 +1,53 @@
+package yaml2tree
+
+type nodeKind int
+
+const (
+	kindScalar nodeKind = iota
+	kindMapping
+	kindSequence
+	kindAlias
+)
+
+type scalarStyle int
+
+const (
+	stylePlain  scalarStyle = iota // plain scalars are resolved to null, bool, int, float or string
+	styleQuoted                    // quoted and block scalars are always string
+)
+
+type yamlNode struct {
+	kind nodeKind
+	line int
+	row  int
+	tag  string
+
+	// scalar
+	value string
+	style scalarStyle
+
+	// mapping
+	pairs []yamlPair
+
+	// sequence
+	items []*yamlNode
+
+	// alias
+	target *yamlNode
+}
+
+type yamlPair struct {
+	key   *yamlNode
+	value *yamlNode
+}
+
+func (node *yamlNode) resolve() *yamlNode {
+	for node.kind == kindAlias {
+		node = node.target
+	}
+	return node
+}
+
+func (node *yamlNode) isMergeKey() bool {
+	return node.kind == kindScalar && node.style == stylePlain && node.value == "<<"
+}
@@ -0,0 +1,1062 @@
+package yaml2tree
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+/*
+parser builds the AST of a single YAML document.
+
+Supported:
+
+	block mappings and block sequences (including indentless sequences as mapping values)
+	flow mappings and flow sequences
+	plain, single-quoted and double-quoted scalars, which may span multiple lines
+	literal ('|') and folded ('>') block scalars with chomping and indentation indicators
+	anchors, aliases and merge keys ("<<")
+	comments, directives and document markers
+
+Not supported:
+
+	multiple documents
+	complex mapping keys ('?')
+*/
+type parser struct {
+	src        []rune
+	pos        int
+	lineStarts []int
+	anchors    map[string]*yamlNode
+}
+
+func newParser(src []rune) *parser {
+	lineStarts := []int{0}
+	for i, char := range src {
+		if char == '\n' {
+			lineStarts = append(lineStarts, i+1)
+		}
+	}
+	return &parser{
+		src:        src,
+		pos:        0,
+		lineStarts: lineStarts,
+		anchors:    make(map[string]*yamlNode),
+	}
+}
+
+// <<==== utils begin ====>
+
+func isBlank(char rune) bool {
+	return char == ' ' || char == '\t'
+}
+
+func isBreakOrEOF(char rune) bool {
+	return char == '\n' || char == 0
+}
+
+func isSpaceOrEnd(char rune) bool {
+	return isBlank(char) || isBreakOrEOF(char)
+}
+
+func isFlowIndicator(char rune) bool {
+	switch char {
+	case ',', '[', ']', '{', '}':
+		return true
+	default:
+		return false
+	}
+}
+
+func (p *parser) position(pos int) (line int, row int) {
+	index := sort.Search(len(p.lineStarts), func(i int) bool {
+		return p.lineStarts[i] > pos
+	}) - 1
+	return index + 1, pos - p.lineStarts[index] + 1
+}
+
+func (p *parser) col() int {
+	_, row := p.position(p.pos)
+	return row - 1
+}
+
+func (p *parser) eof() bool {
+	return p.pos >= len(p.src)
+}
+
+func (p *parser) peekAt(offset int) rune {
+	if p.pos+offset >= len(p.src) {
+		return 0
+	}
+	return p.src[p.pos+offset]
+}
+
+func (p *parser) peek() rune {
+	return p.peekAt(0)
+}
+
+func (p *parser) errorf(format string, args ...interface{}) error {
+	line, row := p.position(p.pos)
+	return &SyntaxError{
+		Line:    line,
+		Row:     row,
+		Message: fmt.Sprintf(format, args...),
+	}
+}
+
+func (p *parser) unexpectError() error {
+	if p.eof() {
+		return p.errorf("unexpected end of file")
+	}
+	return p.errorf("unexpected character '%c'", p.peek())
+}
+
+func (p *parser) newNode(kind nodeKind, pos int) *yamlNode {
+	line, row := p.position(pos)
+	return &yamlNode{
+		kind: kind,
+		line: line,
+		row:  row,
+	}
+}
+
+func (p *parser) newNullNode(pos int) *yamlNode {
+	node := p.newNode(kindScalar, pos)
+	node.value = ""
+	node.style = stylePlain
+	return node
+}
+
+func (p *parser) skipBlanks() {
+	for isBlank(p.peek()) {
+		p.pos++
+	}
+}
+
+func (p *parser) skipComment() {
+	if p.peek() != '#' {
+		return
+	}
+	for !isBreakOrEOF(p.peek()) {
+		p.pos++
+	}
+}
+
+/*
+skipToContent skips blanks, comments and line breaks, and stops at the next content character.
+*/
+func (p *parser) skipToContent() {
+	for {
+		p.skipBlanks()
+		p.skipComment()
+		if p.peek() != '\n' {
+			return
+		}
+		p.pos++
+	}
+}
+
+/*
+skipFlowSpace is the same as skipToContent, it is used in flow context to make the code clear.
+*/
+func (p *parser) skipFlowSpace() {
+	p.skipToContent()
+}
+
+func (p *parser) atLineStart() bool {
+	for i := p.pos - 1; i >= 0; i-- {
+		if p.src[i] == '\n' {
+			return true
+		}
+		if !isBlank(p.src[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+func (p *parser) atLineEnd() bool {
+	save := p.pos
+	p.skipBlanks()
+	p.skipComment()
+	ret := isBreakOrEOF(p.peek())
+	p.pos = save
+	return ret
+}
+
+func (p *parser) expectLineEnd() error {
+	p.skipBlanks()
+	p.skipComment()
+	if !isBreakOrEOF(p.peek()) {
+		return p.unexpectError()
+	}
+	return nil
+}
+
+func (p *parser) isDocumentMarker(char rune) bool {
+	if p.col() != 0 {
+		return false
+	}
+	for i := 0; i < 3; i++ {
+		if p.peekAt(i) != char {
+			return false
+		}
+	}
+	return isSpaceOrEnd(p.peekAt(3))
+}
+
+func (p *parser) isSequenceEntry() bool {
+	return p.peek() == '-' && isSpaceOrEnd(p.peekAt(1))
+}
+
+func (p *parser) isKeyIndicator(flow bool) bool {
+	if p.peek() != ':' {
+		return false
+	}
+	next := p.peekAt(1)
+	return isSpaceOrEnd(next) || (flow && isFlowIndicator(next))
+}
+
+/*
+isBlockKeyStart returns whether the key of a block mapping can start at the position of node,
+only blanks and sequence entry indicators are allowed before it in the same line.
+*/
+func (p *parser) isBlockKeyStart(node *yamlNode) bool {
+	lineStart := p.lineStarts[node.line-1]
+	for i := lineStart + node.row - 2; i >= lineStart; i-- {
+		char := p.src[i]
+		if isBlank(char) {
+			continue
+		}
+		if char == '-' && isBlank(p.src[i+1]) {
+			continue
+		}
+		return false
+	}
+	return true
+}
+
+/*
+checkIndentation returns a syntax error if the content at the current position starts a line
+and is indented with tabs, which is forbidden in YAML.
+*/
+func (p *parser) checkIndentation() error {
+	if p.eof() || !p.atLineStart() {
+		return nil
+	}
+	for i := p.pos - 1; i >= 0 && p.src[i] != '\n'; i-- {
+		if p.src[i] == '\t' {
+			p.pos = i
+			return p.errorf("tab cannot be used for indentation")
+		}
+	}
+	return nil
+}
+
+/*
+contentBelongsTo returns whether the content at the current position is a child of the node
+with the given indent. An indentless sequence is allowed when indentless is true.
+*/
+func (p *parser) contentBelongsTo(indent int, indentless bool) bool {
+	if p.eof() || p.isDocumentMarker('-') || p.isDocumentMarker('.') {
+		return false
+	}
+	col := p.col()
+	return col > indent || (indentless && col == indent && p.isSequenceEntry())
+}
+
+// <----- utils end ----->
+
+// <<==== document begin ====>
+
+func (p *parser) parseDocument() (*yamlNode, error) {
+	if p.peek() == '\uFEFF' {
+		p.pos++
+	}
+	p.skipToContent()
+	for !p.eof() && p.peek() == '%' && p.col() == 0 {
+		// directive
+		for !isBreakOrEOF(p.peek()) {
+			p.pos++
+		}
+		p.skipToContent()
+	}
+	if p.isDocumentMarker('-') {
+		p.pos += 3
+		p.skipBlanks()
+		p.skipComment()
+	}
+	p.skipToContent()
+
+	var root *yamlNode
+	if p.eof() || p.isDocumentMarker('.') || p.isDocumentMarker('-') {
+		root = p.newNullNode(p.pos)
+	} else {
+		var err error
+		root, err = p.parseBlockNode(-1, false)
+		if err != nil {
+			return nil, err
+		}
+		if !p.atLineStart() {
+			if err = p.expectLineEnd(); err != nil {
+				return nil, err
+			}
+		}
+		p.skipToContent()
+	}
+
+	if p.isDocumentMarker('.') {
+		p.pos += 3
+		if err := p.expectLineEnd(); err != nil {
+			return nil, err
+		}
+		p.skipToContent()
+	}
+	if p.isDocumentMarker('-') {
+		return nil, p.errorf("multiple documents are not supported")
+	}
+	if !p.eof() {
+		return nil, p.errorf("unexpected content, may be caused by bad indentation")
+	}
+	return root, nil
+}
+
+// <----- document end ----->
+
+// <<==== block begin ====>
+
+func (p *parser) parseProperties() (anchor string, tag string, err error) {
+	for {
+		switch p.peek() {
+		case '&':
+			p.pos++
+			anchor = p.scanName()
+			if len(anchor) == 0 {
+				return "", "", p.errorf("empty anchor name")
+			}
+		case '!':
+			tag = p.scanName()
+		default:
+			return anchor, tag, nil
+		}
+		p.skipBlanks()
+	}
+}
+
+func (p *parser) scanName() string {
+	start := p.pos
+	for !isSpaceOrEnd(p.peek()) && !isFlowIndicator(p.peek()) {
+		p.pos++
+	}
+	return string(p.src[start:p.pos])
+}
+
+func (p *parser) registerAnchor(anchor string, node *yamlNode) {
+	if len(anchor) != 0 {
+		p.anchors[anchor] = node
+	}
+}
+
+/*
+parseBlockNode parses a node in block context, whose content starts at the current position.
+The content of the node must be indented more than indent, except an indentless sequence
+when indentless is true.
+*/
+func (p *parser) parseBlockNode(indent int, indentless bool) (*yamlNode, error) {
+	if err := p.checkIndentation(); err != nil {
+		return nil, err
+	}
+	start := p.pos
+	anchor, tag, err := p.parseProperties()
+	if err != nil {
+		return nil, err
+	}
+
+	var node *yamlNode
+	if p.pos != start && p.atLineEnd() {
+		// the content is in the following lines
+		p.skipToContent()
+		if err = p.checkIndentation(); err != nil {
+			return nil, err
+		}
+		if p.contentBelongsTo(indent, indentless) {
+			node, err = p.parseBlockContent(indent)
+		} else {
+			node = p.newNullNode(start)
+		}
+	} else {
+		node, err = p.parseBlockContent(indent)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if len(tag) != 0 {
+		node.tag = tag
+	}
+	p.registerAnchor(anchor, node)
+	return node, nil
+}
+
+func (p *parser) parseBlockContent(indent int) (*yamlNode, error) {
+	switch p.peek() {
+	case '-':
+		if p.isSequenceEntry() {
+			return p.parseBlockSequence(p.col())
+		}
+	case '|', '>':
+		return p.parseBlockScalar(indent)
+	case '*':
+		return p.parseAlias()
+	case '[', '{':
+		return p.parseFlowNode()
+	case '?':
+		if isSpaceOrEnd(p.peekAt(1)) {
+			return nil, p.errorf("complex mapping key is not supported")
+		}
+	case '@', '`':
+		return nil, p.errorf("reserved indicator '%c' cannot start a plain scalar", p.peek())
+	}
+
+	col := p.col()
+	var node *yamlNode
+	var err error
+	if p.peek() == '"' || p.peek() == '\'' {
+		node, err = p.parseQuotedScalar()
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		node = p.newNode(kindScalar, p.pos)
+		node.value = p.scanPlainLine(false)
+		node.style = stylePlain
+	}
+
+	save := p.pos
+	p.skipBlanks()
+	if p.isKeyIndicator(false) {
+		if !p.isBlockKeyStart(node) {
+			return nil, p.errorf("mapping values are not allowed in this context")
+		}
+		return p.parseBlockMapping(col, node)
+	}
+	p.pos = save
+
+	if node.style == stylePlain {
+		p.parsePlainContinuation(node, indent)
+	}
+	return node, nil
+}
+
+func (p *parser) parseMappingKey() (*yamlNode, error) {
+	var key *yamlNode
+	var err error
+	switch p.peek() {
+	case '"', '\'':
+		key, err = p.parseQuotedScalar()
+		if err != nil {
+			return nil, err
+		}
+	case '?':
+		if isSpaceOrEnd(p.peekAt(1)) {
+			return nil, p.errorf("complex mapping key is not supported")
+		}
+		fallthrough
+	default:
+		if p.isSequenceEntry() {
+			return nil, p.errorf("sequence entry is not allowed in a mapping")
+		}
+		key = p.newNode(kindScalar, p.pos)
+		key.value = p.scanPlainLine(false)
+		key.style = stylePlain
+	}
+	p.skipBlanks()
+	if !p.isKeyIndicator(false) {
+		return nil, p.errorf("could not find expected ':'")
+	}
+	return key, nil
+}
+
+/*
+parseBlockMapping parses a block mapping whose keys are at column indent, the first key has
+been parsed and the current position is at the ':' after it.
+*/
+func (p *parser) parseBlockMapping(indent int, key *yamlNode) (*yamlNode, error) {
+	node := &yamlNode{
+		kind: kindMapping,
+		line: key.line,
+		row:  key.row,
+	}
+	for {
+		// ':'
+		p.pos++
+		value, err := p.parseMappingValue(indent)
+		if err != nil {
+			return nil, err
+		}
+		node.pairs = append(node.pairs, yamlPair{key: key, value: value})
+
+		if !p.atLineStart() {
+			if err = p.expectLineEnd(); err != nil {
+				return nil, err
+			}
+		}
+		p.skipToContent()
+		if err = p.checkIndentation(); err != nil {
+			return nil, err
+		}
+		if !p.contentBelongsTo(indent-1, false) {
+			break
+		}
+		if p.col() > indent {
+			return nil, p.errorf("bad indentation of a mapping entry")
+		}
+		key, err = p.parseMappingKey()
+		if err != nil {
+			return nil, err
+		}
+	}
+	return node, nil
+}
+
+func (p *parser) parseMappingValue(indent int) (*yamlNode, error) {
+	p.skipBlanks()
+	if p.atLineEnd() {
+		start := p.pos
+		p.skipToContent()
+		if p.contentBelongsTo(indent, true) {
+			return p.parseBlockNode(indent, true)
+		}
+		return p.newNullNode(start), nil
+	}
+	return p.parseBlockNode(indent, true)
+}
+
+/*
+parseBlockSequence parses a block sequence whose entries are at column indent.
+*/
+func (p *parser) parseBlockSequence(indent int) (*yamlNode, error) {
+	node := p.newNode(kindSequence, p.pos)
+	node.items = make([]*yamlNode, 0)
+	for {
+		// '-'
+		p.pos++
+		p.skipBlanks()
+		var item *yamlNode
+		var err error
+		if p.atLineEnd() {
+			start := p.pos
+			p.skipToContent()
+			if p.contentBelongsTo(indent, false) {
+				item, err = p.parseBlockNode(indent, false)
+			} else {
+				item = p.newNullNode(start)
+			}
+		} else {
+			item, err = p.parseBlockNode(indent, false)
+		}
+		if err != nil {
+			return nil, err
+		}
+		node.items = append(node.items, item)
+
+		if !p.atLineStart() {
+			if err = p.expectLineEnd(); err != nil {
+				return nil, err
+			}
+		}
+		p.skipToContent()
+		if err = p.checkIndentation(); err != nil {
+			return nil, err
+		}
+		if !p.contentBelongsTo(indent-1, false) {
+			break
+		}
+		if p.col() > indent {
+			return nil, p.errorf("bad indentation of a sequence entry")
+		}
+		if !p.isSequenceEntry() {
+			// the end of an indentless sequence
+			break
+		}
+	}
+	return node, nil
+}
+
+func (p *parser) parseAlias() (*yamlNode, error) {
+	node := p.newNode(kindAlias, p.pos)
+	// '*'
+	p.pos++
+	node.value = p.scanName()
+	target, ok := p.anchors[node.value]
+	if !ok {
+		return nil, &UndefinedAliasError{
+			Line:  node.line,
+			Row:   node.row,
+			Alias: node.value,
+		}
+	}
+	node.target = target
+	return node, nil
+}
+
+// <----- block end ----->
+
+// <<==== flow begin ====>
+
+func (p *parser) parseFlowNode() (*yamlNode, error) {
+	p.skipFlowSpace()
+	start := p.pos
+	anchor, tag, err := p.parseProperties()
+	if err != nil {
+		return nil, err
+	}
+	if p.pos != start {
+		p.skipFlowSpace()
+	}
+
+	var node *yamlNode
+	switch p.peek() {
+	case '[':
+		node, err = p.parseFlowSequence()
+	case '{':
+		node, err = p.parseFlowMapping()
+	case '*':
+		node, err = p.parseAlias()
+	case '"', '\'':
+		node, err = p.parseQuotedScalar()
+	case ',', ']', '}':
+		node = p.newNullNode(p.pos)
+	case 0:
+		return nil, p.unexpectError()
+	default:
+		node = p.newNode(kindScalar, p.pos)
+		node.value = p.scanPlainLine(true)
+		node.style = stylePlain
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if len(tag) != 0 {
+		node.tag = tag
+	}
+	p.registerAnchor(anchor, node)
+	return node, nil
+}
+
+func (p *parser) parseFlowSequence() (*yamlNode, error) {
+	node := p.newNode(kindSequence, p.pos)
+	node.items = make([]*yamlNode, 0)
+	// '['
+	p.pos++
+	for {
+		p.skipFlowSpace()
+		if p.peek() == ']' {
+			p.pos++
+			return node, nil
+		}
+		item, err := p.parseFlowNode()
+		if err != nil {
+			return nil, err
+		}
+		p.skipFlowSpace()
+		if p.peek() == ':' {
+			// single pair mapping
+			p.pos++
+			p.skipFlowSpace()
+			var value *yamlNode
+			if p.peek() == ',' || p.peek() == ']' {
+				value = p.newNullNode(p.pos)
+			} else if value, err = p.parseFlowNode(); err != nil {
+				return nil, err
+			}
+			item = &yamlNode{
+				kind:  kindMapping,
+				line:  item.line,
+				row:   item.row,
+				pairs: []yamlPair{{key: item, value: value}},
+			}
+			p.skipFlowSpace()
+		}
+		node.items = append(node.items, item)
+
+		switch p.peek() {
+		case ',':
+			p.pos++
+		case ']':
+			p.pos++
+			return node, nil
+		default:
+			return nil, p.unexpectError()
+		}
+	}
+}
+
+func (p *parser) parseFlowMapping() (*yamlNode, error) {
+	node := p.newNode(kindMapping, p.pos)
+	// '{'
+	p.pos++
+	for {
+		p.skipFlowSpace()
+		if p.peek() == '}' {
+			p.pos++
+			return node, nil
+		}
+		key, err := p.parseFlowNode()
+		if err != nil {
+			return nil, err
+		}
+		p.skipFlowSpace()
+		var value *yamlNode
+		if p.peek() == ':' {
+			p.pos++
+			p.skipFlowSpace()
+			if p.peek() == ',' || p.peek() == '}' {
+				value = p.newNullNode(p.pos)
+			} else if value, err = p.parseFlowNode(); err != nil {
+				return nil, err
+			}
+			p.skipFlowSpace()
+		} else {
+			value = p.newNullNode(p.pos)
+		}
+		node.pairs = append(node.pairs, yamlPair{key: key, value: value})
+
+		switch p.peek() {
+		case ',':
+			p.pos++
+		case '}':
+			p.pos++
+			return node, nil
+		default:
+			return nil, p.unexpectError()
+		}
+	}
+}
+
+// <----- flow end ----->
+
+// <<==== scalar begin ====>
+
+/*
+scanPlainLine scans a plain scalar until the end of the line, a comment or a key indicator.
+In flow context, flow indicators also end the scalar.
+*/
+func (p *parser) scanPlainLine(flow bool) string {
+	start := p.pos
+	for !p.eof() {
+		char := p.peek()
+		if char == '\n' || p.isKeyIndicator(flow) {
+			break
+		}
+		if char == '#' && p.pos > start && isBlank(p.src[p.pos-1]) {
+			break
+		}
+		if flow && isFlowIndicator(char) {
+			break
+		}
+		p.pos++
+	}
+	return strings.TrimRight(string(p.src[start:p.pos]), " \t")
+}
+
+/*
+parsePlainContinuation appends the following lines, which are indented more than indent,
+to the plain scalar. Single line break is folded into a space.
+*/
+func (p *parser) parsePlainContinuation(node *yamlNode, indent int) {
+	for {
+		save := p.pos
+		p.skipBlanks()
+		if p.peek() != '\n' {
+			p.pos = save
+			return
+		}
+		breaks := 0
+		for p.peek() == '\n' {
+			p.pos++
+			breaks++
+			p.skipBlanks()
+		}
+		if p.peek() == '#' || !p.contentBelongsTo(indent, false) {
+			p.pos = save
+			return
+		}
+		line := p.scanPlainLine(false)
+		if len(line) == 0 {
+			p.pos = save
+			return
+		}
+		if breaks == 1 {
+			node.value += " "
+		} else {
+			node.value += strings.Repeat("\n", breaks-1)
+		}
+		node.value += line
+	}
+}
+
+/*
+foldQuotedBreaks consumes the line breaks in a quoted scalar, a single line break is folded into
+a space, and the following empty lines are kept as line breaks.
+*/
+func (p *parser) foldQuotedBreaks(builder *strings.Builder) {
+	str := strings.TrimRight(builder.String(), " \t")
+	builder.Reset()
+	builder.WriteString(str)
+
+	breaks := 0
+	for p.peek() == '\n' {
+		p.pos++
+		breaks++
+		p.skipBlanks()
+	}
+	if breaks == 1 {
+		builder.WriteRune(' ')
+	} else {
+		builder.WriteString(strings.Repeat("\n", breaks-1))
+	}
+}
+
+func (p *parser) parseQuotedScalar() (*yamlNode, error) {
+	node := p.newNode(kindScalar, p.pos)
+	node.style = styleQuoted
+	quote := p.peek()
+	p.pos++
+
+	builder := strings.Builder{}
+	for {
+		char := p.peek()
+		switch {
+		case p.eof():
+			return nil, p.errorf("unexpected end of file in quoted scalar")
+		case char == '\n':
+			p.foldQuotedBreaks(&builder)
+			if p.isDocumentMarker('-') || p.isDocumentMarker('.') {
+				return nil, p.errorf("unexpected document marker in quoted scalar")
+			}
+		case char == quote && quote == '\'' && p.peekAt(1) == '\'':
+			builder.WriteRune('\'')
+			p.pos += 2
+		case char == quote:
+			p.pos++
+			node.value = builder.String()
+			return node, nil
+		case char == '\\' && quote == '"':
+			if err := p.parseEscape(&builder); err != nil {
+				return nil, err
+			}
+		default:
+			builder.WriteRune(char)
+			p.pos++
+		}
+	}
+}
+
+func (p *parser) parseEscape(builder *strings.Builder) error {
+	// '\\'
+	p.pos++
+	char := p.peek()
+	p.pos++
+	switch char {
+	case '0':
+		builder.WriteRune(0)
+	case 'a':
+		builder.WriteRune('\a')
+	case 'b':
+		builder.WriteRune('\b')
+	case 't', '\t':
+		builder.WriteRune('\t')
+	case 'n':
+		builder.WriteRune('\n')
+	case 'v':
+		builder.WriteRune('\v')
+	case 'f':
+		builder.WriteRune('\f')
+	case 'r':
+		builder.WriteRune('\r')
+	case 'e':
+		builder.WriteRune('\x1b')
+	case ' ', '"', '/', '\\':
+		builder.WriteRune(char)
+	case 'N':
+		builder.WriteRune('\u0085')
+	case '_':
+		builder.WriteRune(' ')
+	case 'L':
+		builder.WriteRune('\u2028')
+	case 'P':
+		builder.WriteRune('\u2029')
+	case 'x':
+		return p.parseHexEscape(builder, 2)
+	case 'u':
+		return p.parseHexEscape(builder, 4)
+	case 'U':
+		return p.parseHexEscape(builder, 8)
+	case '\n':
+		// escaped line break, the leading blanks of the next line are ignored
+		p.skipBlanks()
+	default:
+		p.pos--
+		return p.errorf("invalid escape character '%c'", char)
+	}
+	return nil
+}
+
+func (p *parser) parseHexEscape(builder *strings.Builder, length int) error {
+	if p.pos+length > len(p.src) {
+		return p.errorf("unexpected end of file in escape sequence")
+	}
+	code, err := strconv.ParseUint(string(p.src[p.pos:p.pos+length]), 16, 32)
+	if err != nil {
+		return p.errorf("invalid escape sequence")
+	}
+	builder.WriteRune(rune(code))
+	p.pos += length
+	return nil
+}
+
+type chomping int
+
+const (
+	chompClip chomping = iota
+	chompStrip
+	chompKeep
+)
+
+/*
+parseBlockScalar parses a literal ('|') or folded ('>') block scalar, whose content must be
+indented more than indent.
+*/
+func (p *parser) parseBlockScalar(indent int) (*yamlNode, error) {
+	node := p.newNode(kindScalar, p.pos)
+	node.style = styleQuoted
+	folded := p.peek() == '>'
+	p.pos++
+
+	// header
+	chomp := chompClip
+	explicit := 0
+	for i := 0; i < 2; i++ {
+		char := p.peek()
+		if char == '-' {
+			chomp = chompStrip
+		} else if char == '+' {
+			chomp = chompKeep
+		} else if '1' <= char && char <= '9' {
+			explicit = int(char - '0')
+		} else {
+			break
+		}
+		p.pos++
+	}
+	if err := p.expectLineEnd(); err != nil {
+		return nil, err
+	}
+	if p.peek() == '\n' {
+		p.pos++
+	}
+
+	contentIndent := -1
+	if explicit != 0 {
+		if indent < 0 {
+			contentIndent = explicit - 1
+		} else {
+			contentIndent = indent + explicit
+		}
+	}
+
+	lines := make([]string, 0)
+	for !p.eof() {
+		lineStart := p.pos
+		spaces := 0
+		for p.peekAt(spaces) == ' ' {
+			spaces++
+		}
+		empty := isBreakOrEOF(p.peekAt(spaces))
+		if !empty {
+			if contentIndent < 0 {
+				if spaces <= indent {
+					break
+				}
+				contentIndent = spaces
+			}
+			if spaces < contentIndent || p.isDocumentMarker('-') || p.isDocumentMarker('.') {
+				break
+			}
+		}
+
+		lineEnd := lineStart
+		for !isBreakOrEOF(p.peekAt(lineEnd - lineStart)) {
+			lineEnd++
+		}
+		if empty || contentIndent < 0 || lineStart+contentIndent > lineEnd {
+			lines = append(lines, "")
+		} else {
+			lines = append(lines, string(p.src[lineStart+contentIndent:lineEnd]))
+		}
+		p.pos = lineEnd
+		if p.peek() == '\n' {
+			p.pos++
+		}
+	}
+
+	last := len(lines) - 1
+	for last >= 0 && len(lines[last]) == 0 {
+		last--
+	}
+	var value string
+	if folded {
+		value = foldLines(lines[:last+1])
+	} else {
+		value = strings.Join(lines[:last+1], "\n")
+	}
+	switch chomp {
+	case chompClip:
+		if last >= 0 {
+			value += "\n"
+		}
+	case chompKeep:
+		if last >= 0 {
+			value += "\n"
+		}
+		value += strings.Repeat("\n", len(lines)-last-1)
+	}
+	node.value = value
+	return node, nil
+}
+
+func foldLines(lines []string) string {
+	builder := strings.Builder{}
+	started := false
+	lastNormal := false
+	breaks := 0
+	for _, line := range lines {
+		if len(line) == 0 {
+			breaks++
+			continue
+		}
+		normal := !isBlank(rune(line[0]))
+		if started {
+			if normal && lastNormal {
+				if breaks == 0 {
+					builder.WriteRune(' ')
+				}
+				builder.WriteString(strings.Repeat("\n", breaks))
+			} else {
+				builder.WriteString(strings.Repeat("\n", breaks+1))
+			}
+		} else {
+			builder.WriteString(strings.Repeat("\n", breaks))
+		}
+		builder.WriteString(line)
+		started = true
+		lastNormal = normal
+		breaks = 0
+	}
+	return builder.String()
+}
+
+// <----- scalar end ----->
@@ -0,0 +1,67 @@
+package yaml2tree
+
+import (
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var (
+	intPattern   = regexp.MustCompile(`^[-+]?[0-9]+$`)
+	floatPattern = regexp.MustCompile(`^[-+]?(\.[0-9]+|[0-9]+(\.[0-9]*)?)([eE][-+]?[0-9]+)?$`)
+)
+
+/*
+resolveScalar resolves a scalar with the rules of YAML 1.2 core schema, the result is one of
+nil, bool, int64, uint64 (only for the integers above math.MaxInt64), float64 and string. Quoted scalars, block scalars and scalars tagged with
+"!!str" or "!" are always string.
+*/
+func resolveScalar(node *yamlNode) interface{} {
+	if node.style != stylePlain || node.tag == "!!str" || node.tag == "!" {
+		return node.value
+	}
+	value := node.value
+	switch value {
+	case "", "~", "null", "Null", "NULL":
+		return nil
+	case "true", "True", "TRUE":
+		return true
+	case "false", "False", "FALSE":
+		return false
+	case ".inf", ".Inf", ".INF", "+.inf", "+.Inf", "+.INF":
+		return math.Inf(1)
+	case "-.inf", "-.Inf", "-.INF":
+		return math.Inf(-1)
+	case ".nan", ".NaN", ".NAN":
+		return math.NaN()
+	}
+
+	if strings.HasPrefix(value, "0x") {
+		if i, err := strconv.ParseInt(value[2:], 16, 64); err == nil {
+			return i
+		}
+		if u, err := strconv.ParseUint(value[2:], 16, 64); err == nil {
+			return u
+		}
+	}
+	if strings.HasPrefix(value, "0o") {
+		if i, err := strconv.ParseInt(value[2:], 8, 64); err == nil {
+			return i
+		}
+	}
+	if intPattern.MatchString(value) {
+		if i, err := strconv.ParseInt(value, 10, 64); err == nil {
+			return i
+		}
+		if u, err := strconv.ParseUint(strings.TrimPrefix(value, "+"), 10, 64); err == nil {
+			return u
+		}
+	}
+	if floatPattern.MatchString(value) {
+		if f, err := strconv.ParseFloat(value, 64); err == nil {
+			return f
+		}
+	}
+	return value
+}
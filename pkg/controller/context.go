@@ -1,7 +1,18 @@
 package controller
 
 import (
+	"fmt"
+	"github.com/SnowPhoenix0105/cfgm/internal/property"
 	"github.com/SnowPhoenix0105/cfgm/internal/tree"
+	"github.com/SnowPhoenix0105/cfgm/internal/tree2obj"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
 )
 
 type ConfigManageCallback func(err error) error
@@ -14,10 +25,26 @@ type registerItem struct {
 }
 
 type ConfigManageContext struct {
+	// lock guards root, layers and buildRoot, which are read by Get(), Explain() and the dumps
+	lock sync.RWMutex
+	// loadLock serializes Init() and Reload(), each of which loads the tree, sets the root and
+	// refills the config objects as a whole
+	loadLock      sync.Mutex
 	root          *tree.Node
+	layers        []*layerSnapshot
+	buildRoot     *tree.Node
+	filePaths     []string
+	includedFiles []string // the files included by the config files in the last load
+	loadError     error
+	record        property.Record
 	configObject  map[string]interface{}
 	options       *ConfigManageContextOptions
 	registerItems []registerItem
+
+	fileSystem fs.FS
+	joinPath   func(elem ...string) string
+	dirPath    func(path string) string
+	isAbsPath  func(path string) bool
 }
 
 func NewConfigManageContext(options *ConfigManageContextOptions) *ConfigManageContext {
@@ -27,13 +54,99 @@ func NewConfigManageContext(options *ConfigManageContextOptions) *ConfigManageCo
 	if len(options.ConfigFilePathPrefix) == 0 {
 		options.ConfigFilePathPrefix = "--config="
 	}
-	return &ConfigManageContext{
+	if len(options.EnvSeparator) == 0 {
+		options.EnvSeparator = "_"
+	}
+	if options.Environ == nil {
+		options.Environ = os.Environ
+	}
+	if options.Warn == nil {
+		// the unknown keys are warned by default, see ConfigManageContextOptions.UnknownKeyPolicy
+		options.Warn = func(err error) {
+			fmt.Fprintln(os.Stderr, err.Error())
+		}
+	}
+	ctx := &ConfigManageContext{
 		root:          tree.NewNode(),
 		options:       options,
 		registerItems: nil,
+		fileSystem:    options.FS,
+		joinPath:      path.Join,
+		dirPath:       path.Dir,
+		isAbsPath:     path.IsAbs,
+	}
+	if ctx.fileSystem == nil {
+		ctx.fileSystem = osFS{}
+		ctx.joinPath = filepath.Join
+		ctx.dirPath = filepath.Dir
+		ctx.isAbsPath = filepath.IsAbs
+	}
+	return ctx
+}
+
+// args is evaluated lazily, so that the changes of os.Args before Init() take effect.
+func (ctx *ConfigManageContext) args() []string {
+	if ctx.options.Args != nil {
+		return ctx.options.Args
+	}
+	return os.Args[1:]
+}
+
+// parseIndex parses a part of path like "[0]".
+func parseIndex(name string) (int, bool) {
+	if len(name) > 2 && name[0] == '[' && name[len(name)-1] == ']' {
+		index, err := strconv.ParseUint(name[1:len(name)-1], 10, 31)
+		if err == nil {
+			return int(index), true
+		}
 	}
+	return 0, false
 }
 
-func (ctx *ConfigManageContext) Get(path string, ptr interface{}) bool {
-	panic("not implement")
+func enterPath(walker tree.ReadonlyWalker, name string) bool {
+	if index, ok := parseIndex(name); ok {
+		return walker.TryEnterList(index)
+	}
+	return walker.TryEnterObj(name)
+}
+
+/*
+Get refills the object pointed by ptr with the value at path of the merged config tree.
+The path is split by '.', and a part like "[0]" means the index of a list, e.g. "db.pool.[0].host".
+
+Errors:
+
+	InvalidTargetError: ptr is not a non-nil pointer
+	PathNotFoundError: there is no value at path
+	TypeMismatchError: the value at path cannot be refilled to the object pointed by ptr
+	RefillError: some values in the object or the list at path mismatch the types of fields
+*/
+func (ctx *ConfigManageContext) Get(path string, ptr interface{}) (bool, error) {
+	value := reflect.ValueOf(ptr)
+	if value.Kind() != reflect.Ptr || value.IsNil() {
+		return false, &InvalidTargetError{Type: reflect.TypeOf(ptr)}
+	}
+
+	ctx.lock.RLock()
+	defer ctx.lock.RUnlock()
+
+	walker := tree.ReadFrom(ctx.root)
+	if len(path) != 0 {
+		for _, p := range strings.Split(path, ".") {
+			if !enterPath(walker, p) {
+				return false, &PathNotFoundError{Path: path}
+			}
+		}
+	}
+	options := ctx.options.refillOptions()
+	if len(path) != 0 {
+		options.Path = strings.Split(path, ".")
+	}
+	if !tree2obj.CanRefill(walker, value.Type().Elem(), options.Mode) {
+		return false, &TypeMismatchError{Path: path, Type: value.Type().Elem()}
+	}
+	if err := tree2obj.RefillFrom(walker, ptr, modifyTimeInvalid, options); err != nil {
+		return false, err
+	}
+	return true, nil
 }
@@ -0,0 +1,388 @@
+package controller
+
+import (
+	"bytes"
+	"github.com/stretchr/testify/assert"
+	"math"
+	"reflect"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+func TestGet(t *testing.T) {
+	type Host struct {
+		Host string
+		Port int
+	}
+	type DB struct {
+		Pool []Host
+		Name string
+	}
+	obj := DB{
+		Pool: []Host{
+			{Host: "localhost", Port: 3306},
+			{Host: "remote", Port: 3307},
+		},
+		Name: "test",
+	}
+	ctx := NewConfigManageContext(&ConfigManageContextOptions{})
+	ctx.Register("db", &obj, func(err error) error { return err })
+	assert.Empty(t, ctx.Init())
+
+	var host string
+	ok, err := ctx.Get("db.Pool.[1].Host", &host)
+	assert.True(t, ok)
+	assert.Nil(t, err)
+	assert.Equal(t, "remote", host)
+
+	var h Host
+	ok, err = ctx.Get("db.Pool.[0]", &h)
+	assert.True(t, ok)
+	assert.Nil(t, err)
+	assert.Equal(t, Host{Host: "localhost", Port: 3306}, h)
+
+	ok, err = ctx.Get("db.Pool.[2]", &h)
+	assert.False(t, ok)
+	assert.IsType(t, &PathNotFoundError{}, err)
+
+	var port string
+	ok, err = ctx.Get("db.Pool.[0].Port", &port)
+	assert.False(t, ok)
+	assert.IsType(t, &TypeMismatchError{}, err)
+
+	ok, err = ctx.Get("db.Name", host)
+	assert.False(t, ok)
+	assert.IsType(t, &InvalidTargetError{}, err)
+}
+
+func TestInit_Env(t *testing.T) {
+	type Pool struct {
+		Size int
+		Host string
+	}
+	type DB struct {
+		Pool    Pool
+		Debug   bool
+		Replica []*Pool
+	}
+	obj := DB{
+		Pool:    Pool{Size: 4, Host: "localhost"},
+		Replica: []*Pool{{Size: 1}},
+	}
+	environ := []string{
+		"APP_DB_POOL_SIZE=16",
+		"APP_DB_DEBUG=true",
+		"APP_DB_REPLICA_0_HOST=replica",
+		"APP_DB_UNKNOWN=ignored",
+		"OTHER_DB_POOL_HOST=ignored",
+	}
+
+	ctx := NewConfigManageContext(&ConfigManageContextOptions{
+		Args:      []string{"-Ddb.Pool.Host=remote"},
+		Environ:   func() []string { return environ },
+		EnvPrefix: "APP",
+	})
+	ctx.Register("db", &obj, func(err error) error { return err })
+	assert.Empty(t, ctx.Init())
+	assert.Equal(t, Pool{Size: 16, Host: "remote"}, obj.Pool)
+	assert.True(t, obj.Debug)
+	assert.Equal(t, &Pool{Size: 1, Host: "replica"}, obj.Replica[0])
+}
+
+func TestInit_InjectedSources(t *testing.T) {
+	type Server struct {
+		Host    string
+		Port    int
+		Timeout int
+		Debug   bool
+	}
+	fileSystem := fstest.MapFS{
+		"etc/app/base.json":        {Data: []byte(`{"server": {"Host": "base", "Port": 80}}`)},
+		"etc/app/conf.d/port.yaml": {Data: []byte("server:\n  Port: 8080\n")},
+	}
+	server := Server{}
+	ctx := NewConfigManageContext(&ConfigManageContextOptions{
+		CommandLinePrefix:    "--set=",
+		ConfigFilePathPrefix: "--file=",
+		Args: []string{
+			"--file=etc/app/base.json",
+			"--file=etc/app/conf.d",
+			"--set=server.Debug=true",
+			"--config=ignored.json",
+			"-Dserver.Host=ignored",
+		},
+		Environ: func() []string {
+			return []string{"APP_SERVER_TIMEOUT=30"}
+		},
+		EnvPrefix: "APP",
+		FS:        fileSystem,
+	})
+	ctx.Register("server", &server, func(err error) error { return err })
+	assert.Empty(t, ctx.Init())
+	assert.Equal(t, Server{Host: "base", Port: 8080, Timeout: 30, Debug: true}, server)
+}
+
+func TestInit_Validation(t *testing.T) {
+	type Server struct {
+		Host string `cfgm:"required"`
+		Port int    `cfgm:"min=1"`
+	}
+	server := Server{Host: "localhost", Port: 80}
+	var received error
+	ctx := NewConfigManageContext(&ConfigManageContextOptions{
+		Args: []string{"-Dserver.Port=0"},
+	})
+	ctx.Register("server", &server, func(err error) error {
+		received = err
+		return err
+	})
+	errs := ctx.Init()
+	assert.Len(t, errs, 1)
+	assert.Equal(t, &ValidationError{Fields: []*FieldError{
+		{Path: "server.Port", Value: "0", Source: "-Dserver.Port=0", Rule: "min=1", Message: "must be at least 1"},
+	}}, received)
+}
+
+func TestInit_TypeMatching(t *testing.T) {
+	type Server struct {
+		Host string
+		Port int
+	}
+	args := []string{`-Dserver.Host=localhost`, `-Dserver.Port="8080"`}
+
+	server := Server{}
+	var received error
+	ctx := NewConfigManageContext(&ConfigManageContextOptions{Args: args})
+	ctx.Register("server", &server, func(err error) error {
+		received = err
+		return err
+	})
+	errs := ctx.Init()
+	assert.Len(t, errs, 1)
+	assert.Equal(t, &RefillError{Mismatches: []*MismatchError{
+		{Path: "server.Port", Type: reflect.TypeOf(0), Value: `"8080"`, Source: `-Dserver.Port="8080"`},
+	}}, received)
+
+	server = Server{}
+	ctx = NewConfigManageContext(&ConfigManageContextOptions{Args: args, TypeMatching: TypeMatchingLenient})
+	ctx.Register("server", &server, func(err error) error {
+		return err
+	})
+	errs = ctx.Init()
+	assert.Len(t, errs, 0)
+	assert.Equal(t, Server{Host: "localhost", Port: 8080}, server)
+}
+
+func TestInit_SecretReferences(t *testing.T) {
+	type DB struct {
+		User     string
+		Password string
+	}
+	fileSystem := fstest.MapFS{
+		"config.json":         {Data: []byte(`{"db": {"User": "${env:DB_USER}", "Password": "${file:secrets/db"}}`)},
+		"secrets/db_password": {Data: []byte("p@ss\n")},
+	}
+	environ := func() []string { return []string{"DB_USER=admin"} }
+
+	db := DB{}
+	ctx := NewConfigManageContext(&ConfigManageContextOptions{
+		Args:    []string{"--config=config.json", "-Ddb.Password=${file:secrets/db_password}"},
+		Environ: environ,
+		FS:      fileSystem,
+	})
+	ctx.Register("db", &db, func(err error) error { return err })
+	assert.Empty(t, ctx.Init())
+	assert.Equal(t, DB{User: "admin", Password: "p@ss"}, db)
+
+	db = DB{}
+	ctx = NewConfigManageContext(&ConfigManageContextOptions{
+		Args:    []string{"--config=config.json"},
+		Environ: environ,
+		FS:      fileSystem,
+	})
+	ctx.Register("db", &db, func(err error) error { return err })
+	errs := ctx.Init()
+	assert.Len(t, errs, 1)
+	refErr, ok := errs[0].(*ReferenceError)
+	assert.True(t, ok)
+	assert.Equal(t, "db.Password", refErr.Path)
+	assert.Equal(t, "${file:secrets/db", refErr.Reference)
+}
+
+func TestInit_ReferencesInDefaults(t *testing.T) {
+	type DB struct {
+		User     string
+		Password string `secret:"true"`
+		Key      string
+	}
+	fileSystem := fstest.MapFS{
+		"secrets/db_key": {Data: []byte("k3y\n")},
+	}
+	db := DB{User: "admin", Password: "${env:DB_PASSWORD}", Key: "${file:secrets/db_key}"}
+	ctx := NewConfigManageContext(&ConfigManageContextOptions{
+		Args:    []string{},
+		Environ: func() []string { return []string{"DB_PASSWORD=p@ss"} },
+		FS:      fileSystem,
+	})
+	ctx.Register("db", &db, func(err error) error { return err })
+	assert.Empty(t, ctx.Init())
+	assert.Equal(t, DB{User: "admin", Password: "p@ss", Key: "k3y"}, db)
+
+	password := ""
+	ok, err := ctx.Get("db.Password", &password)
+	assert.True(t, ok)
+	assert.Nil(t, err)
+	assert.Equal(t, db.Password, password)
+}
+
+func TestInit_PathReferencesInDefaults(t *testing.T) {
+	type Endpoint struct {
+		Host string
+		Port int
+	}
+	type Server struct {
+		Dir     string
+		LogDir  string
+		Primary Endpoint
+		Backup  Endpoint
+	}
+	server := Server{
+		Dir:     "/var/app",
+		LogDir:  "${server.Dir}/log",
+		Primary: Endpoint{Host: "localhost", Port: 8080},
+		Backup:  Endpoint{Host: "${server.Primary.Host}"},
+	}
+	ctx := NewConfigManageContext(&ConfigManageContextOptions{
+		Args: []string{"-Dserver.Dir=/opt/app", "-Dserver.Backup=${server.Primary}"},
+	})
+	ctx.Register("server", &server, func(err error) error { return err })
+	assert.Empty(t, ctx.Init())
+	assert.Equal(t, Server{
+		Dir:     "/opt/app",
+		LogDir:  "/opt/app/log",
+		Primary: Endpoint{Host: "localhost", Port: 8080},
+		Backup:  Endpoint{Host: "localhost", Port: 8080},
+	}, server)
+
+	// the registered object agrees with Get and DumpEffective
+	logDir := ""
+	ok, err := ctx.Get("server.LogDir", &logDir)
+	assert.True(t, ok)
+	assert.Nil(t, err)
+	assert.Equal(t, server.LogDir, logDir)
+	buf := bytes.Buffer{}
+	assert.Nil(t, ctx.DumpEffective(&buf))
+	assert.Contains(t, buf.String(), `"/opt/app/log"`)
+}
+
+func TestInit_UnknownPathReferences(t *testing.T) {
+	type Job struct {
+		Home    string
+		Command string
+		Log     string
+	}
+	job := Job{Home: "${HOME}", Command: "cd ${HOME}/data && ./run.sh"}
+	ctx := NewConfigManageContext(&ConfigManageContextOptions{
+		Args:    []string{"-Djob.Log=[${level}] ${job.Home}"},
+		Environ: func() []string { return []string{"HOME=/root"} },
+	})
+	ctx.Register("job", &job, func(err error) error { return err })
+	assert.Empty(t, ctx.Init())
+	assert.Equal(t, Job{Home: "${HOME}", Command: "cd ${HOME}/data && ./run.sh", Log: "[${level}] ${HOME}"}, job)
+}
+
+func TestInit_Time(t *testing.T) {
+	type Server struct {
+		Timeout time.Duration
+		Idle    time.Duration
+		Since   time.Time
+	}
+	server := Server{Timeout: time.Second, Idle: time.Minute}
+	ctx := NewConfigManageContext(&ConfigManageContextOptions{
+		Args:      []string{"-Dserver.Timeout=1m30s", "-Dserver.Since=2021-06-01T12:00:00Z"},
+		Environ:   func() []string { return []string{"APP_SERVER_IDLE=5m"} },
+		EnvPrefix: "APP",
+	})
+	ctx.Register("server", &server, func(err error) error { return err })
+	assert.Empty(t, ctx.Init())
+	assert.Equal(t, Server{
+		Timeout: 90 * time.Second,
+		Idle:    5 * time.Minute,
+		Since:   time.Date(2021, 6, 1, 12, 0, 0, 0, time.UTC),
+	}, server)
+}
+
+func TestInit_Naming(t *testing.T) {
+	type Server struct {
+		HTTPPort    int `cfgm:",min=1"`
+		MaxIdleTime int
+		Name        string `cfgm:"server_name,required"`
+		Internal    string `cfgm:"-"`
+	}
+	fileSystem := fstest.MapFS{
+		"app.json": {Data: []byte(`{"server": {"http_port": 8080, "server_name": "api", "internal": "ignored"}}`)},
+	}
+	warnings := make([]error, 0)
+	server := Server{Internal: "internal"}
+	ctx := NewConfigManageContext(&ConfigManageContextOptions{
+		Args:      []string{"--config=app.json"},
+		Environ:   func() []string { return []string{"APP_SERVER_MAX_IDLE_TIME=30"} },
+		EnvPrefix: "APP",
+		FS:        fileSystem,
+		Naming:    NamingSnakeCase,
+		Warn:      func(err error) { warnings = append(warnings, err) },
+	})
+	ctx.Register("server", &server, func(err error) error { return err })
+	assert.Empty(t, ctx.Init())
+	assert.Equal(t, Server{HTTPPort: 8080, MaxIdleTime: 30, Name: "api", Internal: "internal"}, server)
+	assert.Len(t, warnings, 1)
+	assert.IsType(t, &UnknownKeyError{}, warnings[0])
+
+	port := 0
+	ok, err := ctx.Get("server.http_port", &port)
+	assert.True(t, ok)
+	assert.Nil(t, err)
+	assert.Equal(t, 8080, port)
+
+	buf := bytes.Buffer{}
+	assert.Nil(t, ctx.DumpTemplate(&buf, TemplateFormatJSON))
+	assert.Contains(t, buf.String(), `"max_idle_time": 0`)
+	assert.Contains(t, buf.String(), `"server_name": ""`)
+	assert.NotContains(t, buf.String(), "nternal")
+
+	ctx = NewConfigManageContext(&ConfigManageContextOptions{
+		Args:   []string{"-Dserver.http_port=0", "-Dserver.server_name=api"},
+		Naming: NamingSnakeCase,
+	})
+	var received error
+	ctx.Register("server", &Server{}, func(err error) error {
+		received = err
+		return err
+	})
+	assert.Len(t, ctx.Init(), 1)
+	assert.Equal(t, &ValidationError{Fields: []*FieldError{
+		{Path: "server.http_port", Value: "0", Source: "-Dserver.http_port=0", Rule: "min=1", Message: "must be at least 1"},
+	}}, received)
+}
+
+func TestInit_IntegerLiterals(t *testing.T) {
+	type Limits struct {
+		Hex    int
+		Octal  int
+		Binary int
+		Large  uint64
+	}
+	limits := Limits{}
+	ctx := NewConfigManageContext(&ConfigManageContextOptions{
+		Args: []string{
+			"-Dlimits.Hex=0x1F",
+			"-Dlimits.Octal=017",
+			"-Dlimits.Binary=0b101",
+			"-Dlimits.Large=0xFFFFFFFFFFFFFFFF",
+		},
+	})
+	ctx.Register("limits", &limits, func(err error) error { return err })
+	assert.Empty(t, ctx.Init())
+	assert.Equal(t, Limits{Hex: 31, Octal: 15, Binary: 5, Large: math.MaxUint64}, limits)
+}
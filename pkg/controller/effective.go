@@ -0,0 +1,30 @@
+package controller
+
+import (
+	"github.com/SnowPhoenix0105/cfgm/internal/tree2json"
+	"io"
+)
+
+/*
+DumpEffective writes the merged config tree which the config objects are refilled with to writer
+as JSON. Each value is followed by a comment of where it comes from, e.g. "config.json:3:5",
+"$APP_DB_PORT", "-Ddb.Port=3306", or "default" for the values of the config objects. The secret
+values are redacted as "******", see DumpTemplate.
+
+Errors:
+
+	NotInitializedError: Init() has not been called successfully
+	other errors returned by writer
+*/
+func (ctx *ConfigManageContext) DumpEffective(writer io.Writer) error {
+	ctx.lock.RLock()
+	defer ctx.lock.RUnlock()
+	if ctx.buildRoot == nil {
+		return &NotInitializedError{Operation: "dump effective"}
+	}
+	_, err := tree2json.DumpToWriterWithOptions(ctx.root, writer, &tree2json.DumpOptions{
+		Sources:        true,
+		SkipPrototypes: true,
+	})
+	return err
+}
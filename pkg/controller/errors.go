@@ -0,0 +1,108 @@
+package controller
+
+import (
+	"fmt"
+	"github.com/SnowPhoenix0105/cfgm/internal/resolve"
+	"github.com/SnowPhoenix0105/cfgm/internal/tree2obj"
+	"github.com/SnowPhoenix0105/cfgm/internal/validate"
+	"reflect"
+	"strings"
+)
+
+type InvalidTargetError struct {
+	Type reflect.Type
+}
+
+func (err *InvalidTargetError) Error() string {
+	if err.Type == nil {
+		return "cfgm get error: target is nil"
+	}
+	return fmt.Sprintf("cfgm get error: target must be a non-nil pointer, but got %s", err.Type.String())
+}
+
+type PathNotFoundError struct {
+	Path string
+}
+
+func (err *PathNotFoundError) Error() string {
+	return fmt.Sprintf("cfgm get error: path (%s) not found", err.Path)
+}
+
+type TypeMismatchError struct {
+	Path string
+	Type reflect.Type
+}
+
+func (err *TypeMismatchError) Error() string {
+	return fmt.Sprintf("cfgm get error: value at path (%s) cannot be used as %s", err.Path, err.Type.String())
+}
+
+type NotInitializedError struct {
+	Operation string
+}
+
+func (err *NotInitializedError) Error() string {
+	return fmt.Sprintf("cfgm %s error: Init() has not been called successfully", err.Operation)
+}
+
+type UnsupportedFormatError struct {
+	Format string
+}
+
+func (err *UnsupportedFormatError) Error() string {
+	return fmt.Sprintf("cfgm dump error: unsupported format (%s)", err.Format)
+}
+
+type InvalidLevelError struct {
+	Level string
+}
+
+func (err *InvalidLevelError) Error() string {
+	return fmt.Sprintf("cfgm dump error: invalid level (%s), expect required, common or advanced", err.Level)
+}
+
+type UnknownKeyError struct {
+	Path       string
+	Source     string
+	Suggestion string
+}
+
+func (err *UnknownKeyError) Error() string {
+	builder := strings.Builder{}
+	builder.WriteString(fmt.Sprintf("cfgm: unknown key (%s)", err.Path))
+	if len(err.Source) != 0 {
+		builder.WriteString(fmt.Sprintf(" from %s", err.Source))
+	}
+	if len(err.Suggestion) != 0 {
+		builder.WriteString(fmt.Sprintf(", did you mean (%s)?", err.Suggestion))
+	}
+	return builder.String()
+}
+
+type UnknownKeysError struct {
+	Keys []*UnknownKeyError
+}
+
+func (err *UnknownKeysError) Error() string {
+	messages := make([]string, len(err.Keys))
+	for i, key := range err.Keys {
+		messages[i] = key.Error()
+	}
+	return strings.Join(messages, "\n")
+}
+
+// The errors of the rules in the "cfgm" tags, which are passed to the callbacks after refilling.
+type (
+	ValidationError  = validate.ValidationError
+	FieldError       = validate.FieldError
+	InvalidRuleError = validate.InvalidRuleError
+)
+
+// The errors of the values which mismatch the types of fields, which are passed to the callbacks.
+type (
+	RefillError   = tree2obj.RefillError
+	MismatchError = tree2obj.MismatchError
+)
+
+// ReferenceError is returned by Init() and Reload() when a reference like "${env:NAME}" fails.
+type ReferenceError = resolve.ReferenceError
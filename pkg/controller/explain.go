@@ -0,0 +1,98 @@
+package controller
+
+import (
+	"github.com/SnowPhoenix0105/cfgm/internal/tree"
+	"github.com/SnowPhoenix0105/cfgm/internal/tree2json"
+	"strings"
+)
+
+// ExplainedLayer is a layer which sets the value, the Value is formatted as JSON.
+type ExplainedLayer struct {
+	// Layer is "default", the path of a config file, "env" or "cmd".
+	Layer string
+	Value string
+	// Source is where the value comes from, e.g. "config.json:3:5", "$APP_DB_HOST" or
+	// "-Ddb.host=localhost". It is empty for the default value.
+	Source string
+}
+
+// Explanation tells the value at a path, and the layers which set it in order, the last one wins.
+type Explanation struct {
+	Path   string
+	Value  string
+	Source string
+	Layers []*ExplainedLayer
+}
+
+/*
+setBy returns whether the node is set by the layer modified at time. The modify times of the
+values are inherited by their parents, so a container is set only when any value in it is set, or
+when it is empty and set itself.
+*/
+func setBy(node *tree.Node, time tree.ModifyTime) bool {
+	children := make([]*tree.Node, 0)
+	if node.Has(tree.NodeKeyObj) {
+		for _, child := range node.Obj() {
+			children = append(children, child)
+		}
+	}
+	if node.Has(tree.NodeKeyList) {
+		children = append(children, node.List()...)
+	}
+	if len(children) == 0 {
+		return node.ModifyTime() == time
+	}
+	for _, child := range children {
+		if setBy(child, time) {
+			return true
+		}
+	}
+	return false
+}
+
+/*
+Explain tells where the value at path comes from. The layers are the ones recorded when the
+current config was loaded by Init() or Reload(), so the config files are not read again.
+
+Errors:
+
+	NotInitializedError: Init() has not been called successfully
+	PathNotFoundError: there is no value at path
+*/
+func (ctx *ConfigManageContext) Explain(path string) (*Explanation, error) {
+	parts := make([]string, 0)
+	if len(path) != 0 {
+		parts = strings.Split(path, ".")
+	}
+
+	ctx.lock.RLock()
+	initialized := ctx.buildRoot != nil
+	node := subtree(ctx.root, parts)
+	layers := ctx.layers
+	ctx.lock.RUnlock()
+	if !initialized {
+		return nil, &NotInitializedError{Operation: "explain"}
+	}
+	if node == nil {
+		return nil, &PathNotFoundError{Path: path}
+	}
+	explanation := &Explanation{
+		Path:   path,
+		Value:  tree2json.DumpToString(node),
+		Source: node.Source(),
+		Layers: make([]*ExplainedLayer, 0),
+	}
+
+	for _, snapshot := range layers {
+		node := subtree(snapshot.root, parts)
+		if node == nil || !setBy(node, snapshot.time) {
+			continue
+		}
+		explanation.Layers = append(explanation.Layers, &ExplainedLayer{
+			Layer:  snapshot.layer,
+			Value:  tree2json.DumpToString(node),
+			Source: node.Source(),
+		})
+	}
+	return explanation, nil
+}
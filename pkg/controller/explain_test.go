@@ -0,0 +1,105 @@
+package controller
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"testing/fstest"
+)
+
+func TestExplain(t *testing.T) {
+	type Server struct {
+		Host  string
+		Port  int
+		Debug bool
+	}
+	fileSystem := fstest.MapFS{
+		"base.json":  {Data: []byte("{\n\t\"server\": {\"Port\": 80}\n}")},
+		"local.yaml": {Data: []byte("server:\n  Port: 8080\n")},
+	}
+	server := Server{Host: "localhost"}
+	ctx := NewConfigManageContext(&ConfigManageContextOptions{
+		Args:      []string{"--config=base.json", "--config=local.yaml", "-Dserver.Port=9090"},
+		Environ:   func() []string { return []string{"APP_SERVER_PORT=8888"} },
+		EnvPrefix: "APP",
+		FS:        fileSystem,
+	})
+	_, err := ctx.Explain("server.Port")
+	assert.Equal(t, &NotInitializedError{Operation: "explain"}, err)
+
+	ctx.Register("server", &server, func(err error) error { return err })
+	assert.Empty(t, ctx.Init())
+
+	explanation, err := ctx.Explain("server.Port")
+	assert.Nil(t, err)
+	assert.Equal(t, &Explanation{
+		Path:   "server.Port",
+		Value:  "9090",
+		Source: "-Dserver.Port=9090",
+		Layers: []*ExplainedLayer{
+			{Layer: "default", Value: "0"},
+			{Layer: "base.json", Value: "80", Source: "base.json:2:13"},
+			{Layer: "local.yaml", Value: "8080", Source: "local.yaml:2:3"},
+			{Layer: "env", Value: "8888", Source: "$APP_SERVER_PORT"},
+			{Layer: "cmd", Value: "9090", Source: "-Dserver.Port=9090"},
+		},
+	}, explanation)
+
+	explanation, err = ctx.Explain("server.Host")
+	assert.Nil(t, err)
+	assert.Equal(t, []*ExplainedLayer{{Layer: "default", Value: `"localhost"`}}, explanation.Layers)
+
+	// the layers are the ones of the loaded config, not of the files changed after loading
+	fileSystem["local.yaml"] = &fstest.MapFile{Data: []byte("server:\n  Port: 7070\n  Debug: true\n")}
+	explanation, err = ctx.Explain("server.Port")
+	assert.Nil(t, err)
+	assert.Equal(t, "8080", explanation.Layers[2].Value)
+	explanation, err = ctx.Explain("server.Debug")
+	assert.Nil(t, err)
+	assert.Equal(t, []*ExplainedLayer{{Layer: "default", Value: "false"}}, explanation.Layers)
+
+	assert.Empty(t, ctx.Reload())
+	explanation, err = ctx.Explain("server.Debug")
+	assert.Nil(t, err)
+	assert.Equal(t, "true", explanation.Value)
+	assert.Equal(t, "true", explanation.Layers[len(explanation.Layers)-1].Value)
+
+	_, err = ctx.Explain("server.Unknown")
+	assert.Equal(t, &PathNotFoundError{Path: "server.Unknown"}, err)
+}
+
+func TestExplain_Object(t *testing.T) {
+	type Server struct {
+		Host  string
+		Port  int
+		Hosts []string
+	}
+	fileSystem := fstest.MapFS{
+		"base.json":  {Data: []byte(`{"server": {"Port": 80, "Hosts": ["a", "b"]}}`)},
+		"empty.json": {Data: []byte(`{"server": {}}`)},
+	}
+	server := Server{Host: "localhost"}
+	ctx := NewConfigManageContext(&ConfigManageContextOptions{
+		Args:    []string{"--config=base.json", "--config=empty.json", "-Dserver.Host=example.com"},
+		Environ: func() []string { return []string{} },
+		FS:      fileSystem,
+	})
+	ctx.Register("server", &server, func(err error) error { return err })
+	assert.Empty(t, ctx.Init())
+
+	// the layers which set nothing in the object are not listed
+	explanation, err := ctx.Explain("server")
+	assert.Nil(t, err)
+	layers := make([]string, 0)
+	for _, layer := range explanation.Layers {
+		layers = append(layers, layer.Layer)
+	}
+	assert.Equal(t, []string{"default", "base.json", "cmd"}, layers)
+
+	explanation, err = ctx.Explain("server.Hosts.[1]")
+	assert.Nil(t, err)
+	assert.Equal(t, `"b"`, explanation.Value)
+	assert.Equal(t, []*ExplainedLayer{{Layer: "base.json", Value: `"b"`, Source: "base.json:1:40"}}, explanation.Layers)
+
+	_, err = ctx.Explain("server.Hosts.[2]")
+	assert.Equal(t, &PathNotFoundError{Path: "server.Hosts.[2]"}, err)
+}
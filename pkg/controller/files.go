@@ -0,0 +1,166 @@
+package controller
+
+import (
+	"fmt"
+	"github.com/SnowPhoenix0105/cfgm/internal/tree"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+/*
+osFS reads files from the operating system. Unlike os.DirFS, it accepts the native paths, e.g.
+absolute paths and paths with "..", which are common in command lines.
+*/
+type osFS struct{}
+
+func (osFS) Open(name string) (fs.File, error) {
+	return os.Open(name)
+}
+
+func (osFS) Stat(name string) (fs.FileInfo, error) {
+	return os.Stat(name)
+}
+
+func (osFS) ReadDir(name string) ([]fs.DirEntry, error) {
+	return os.ReadDir(name)
+}
+
+func (osFS) Glob(pattern string) ([]string, error) {
+	return filepath.Glob(pattern)
+}
+
+/*
+expandConfigPaths replaces the directories in paths with the supported config files in them,
+which are sorted in lexical order. The sub-directories are not searched.
+*/
+func (ctx *ConfigManageContext) expandConfigPaths(paths []string) ([]string, error) {
+	files := make([]string, 0, len(paths))
+	for _, path := range paths {
+		info, err := fs.Stat(ctx.fileSystem, path)
+		if err != nil {
+			return nil, err
+		}
+		if !info.IsDir() {
+			files = append(files, path)
+			continue
+		}
+		// ReadDir returns the entries sorted by file name
+		entries, err := fs.ReadDir(ctx.fileSystem, path)
+		if err != nil {
+			return nil, err
+		}
+		for _, entry := range entries {
+			if entry.IsDir() || ctx.mergeFuncFor(entry.Name()) == nil {
+				continue
+			}
+			files = append(files, ctx.joinPath(path, entry.Name()))
+		}
+	}
+	return files, nil
+}
+
+/*
+mergeTreeByFileConfigs merges the config files in order, each file is merged with its own modify
+time, so that it covers the former ones as a new layer, e.g. a map or a slice in a later file
+replaces the one in a former file.
+*/
+func (ctx *ConfigManageContext) mergeTreeByFileConfigs(root *tree.Node, paths []string, observe layerObserver) error {
+	files, err := ctx.expandConfigPaths(paths)
+	if err != nil {
+		return err
+	}
+	if len(files) > maxConfigFileCount {
+		return fmt.Errorf("too many config files: %d, at most %d", len(files), maxConfigFileCount)
+	}
+	for i, file := range files {
+		time := modifyTimeMerge + tree.ModifyTime(i)
+		err = ctx.mergeTreeByFileConfig(root, file, time)
+		if err != nil {
+			return err
+		}
+		observe(file, root, time)
+	}
+	return nil
+}
+
+/*
+includeFiles finds the files matching pattern, which is relative to the directory of fileName
+unless it is absolute. The pattern must match at least one file. The files found are recorded in
+includedFiles, so that they are watched too.
+*/
+func (ctx *ConfigManageContext) includeFiles(fileName, pattern string) ([]string, error) {
+	if !ctx.isAbsPath(pattern) {
+		pattern = ctx.joinPath(ctx.dirPath(fileName), pattern)
+	}
+	files, err := fs.Glob(ctx.fileSystem, pattern)
+	if err != nil {
+		return nil, err
+	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("no file matches %s", pattern)
+	}
+	sort.Strings(files)
+	ctx.includedFiles = append(ctx.includedFiles, files...)
+	return files, nil
+}
+
+type fileStat struct {
+	path    string
+	modTime time.Time
+	size    int64
+}
+
+func (ctx *ConfigManageContext) statFile(filePath string) fileStat {
+	info, err := fs.Stat(ctx.fileSystem, filePath)
+	if err != nil {
+		return fileStat{path: filePath}
+	}
+	return fileStat{
+		path:    filePath,
+		modTime: info.ModTime(),
+		size:    info.Size(),
+	}
+}
+
+/*
+statConfigFiles stats the config file paths and the config files in the directories, so that adding,
+removing or modifying a config file in a directory can be found. The files included by the config
+files in the last load are stated too. The paths are read under loadLock, so the paths parsed by
+the latest Init() or Reload() are used.
+*/
+func (ctx *ConfigManageContext) statConfigFiles() []fileStat {
+	ctx.loadLock.Lock()
+	paths := ctx.filePaths
+	included := ctx.includedFiles
+	ctx.loadLock.Unlock()
+
+	stats := make([]fileStat, 0, len(paths))
+	for _, path := range paths {
+		stats = append(stats, ctx.statFile(path))
+	}
+	files, err := ctx.expandConfigPaths(paths)
+	if err == nil {
+		for _, file := range files {
+			stats = append(stats, ctx.statFile(file))
+		}
+	}
+	for _, file := range included {
+		stats = append(stats, ctx.statFile(file))
+	}
+	return stats
+}
+
+func sameFileStats(left, right []fileStat) bool {
+	if len(left) != len(right) {
+		return false
+	}
+	for i := range left {
+		if left[i] != right[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,90 @@
+package controller
+
+import (
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestInit_MultipleConfigFiles(t *testing.T) {
+	type Server struct {
+		Host   string
+		Port   int
+		Labels map[string]string
+		Routes []string
+	}
+	dir, err := ioutil.TempDir("", "cfgm")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+	confDir := filepath.Join(dir, "conf.d")
+	assert.Nil(t, os.MkdirAll(filepath.Join(confDir, "sub"), 0755))
+	files := map[string]string{
+		"base.json":               `{"server": {"Host": "base", "Port": 80, "Labels": {"a": "1"}, "Routes": ["/a", "/b"]}}`,
+		"conf.d/20-labels.yaml":   "server:\n  Labels:\n    b: '2'\n",
+		"conf.d/10-port.json":     `{"server": {"Port": 8080, "Routes": ["/c"]}}`,
+		"conf.d/README.txt":       `not a config file`,
+		"conf.d/sub/ignored.json": `{"server": {"Port": 1}}`,
+	}
+	for name, content := range files {
+		assert.Nil(t, ioutil.WriteFile(filepath.Join(dir, name), []byte(content), 0644))
+	}
+
+	server := Server{}
+	ctx := NewConfigManageContext(&ConfigManageContextOptions{
+		Args: []string{"--config=" + filepath.Join(dir, "base.json"), "--config=" + confDir},
+	})
+	ctx.Register("server", &server, func(err error) error { return err })
+	assert.Empty(t, ctx.Init())
+	assert.Equal(t, Server{
+		Host:   "base",
+		Port:   8080,
+		Labels: map[string]string{"b": "2"},
+		Routes: []string{"/c"},
+	}, server)
+
+	// a new file in the directory is merged when reloading
+	assert.Nil(t, ioutil.WriteFile(filepath.Join(confDir, "30-host.toml"), []byte("[server]\nHost = 'override'\n"), 0644))
+	assert.Empty(t, ctx.Reload())
+	assert.Equal(t, "override", server.Host)
+	assert.Equal(t, 8080, server.Port)
+}
+
+func TestInit_IncludeFiles(t *testing.T) {
+	type Server struct {
+		Host string
+		Port int
+	}
+	dir, err := ioutil.TempDir("", "cfgm")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+	assert.Nil(t, os.MkdirAll(filepath.Join(dir, "fragments"), 0755))
+	files := map[string]string{
+		"app.json":              `{"$include": "fragments/*.json", "server": {"Port": 8080}}`,
+		"fragments/host.json":   `{"server": {"Host": "fragment", "Port": 80}}`,
+		"fragments/broken.json": `{"$include": "missing.json"}`,
+	}
+	for name, content := range files {
+		assert.Nil(t, ioutil.WriteFile(filepath.Join(dir, name), []byte(content), 0644))
+	}
+
+	server := Server{}
+	ctx := NewConfigManageContext(&ConfigManageContextOptions{
+		Args: []string{"--config=" + filepath.Join(dir, "app.json")},
+	})
+	ctx.Register("server", &server, func(err error) error { return err })
+	errs := ctx.Init()
+	assert.Len(t, errs, 1)
+	assert.Contains(t, errs[0].Error(), "app.json -> "+filepath.Join(dir, "fragments", "broken.json"))
+	assert.Contains(t, errs[0].Error(), "no file matches "+filepath.Join(dir, "fragments", "missing.json"))
+
+	assert.Nil(t, os.Remove(filepath.Join(dir, "fragments", "broken.json")))
+	server = Server{}
+	ctx = NewConfigManageContext(&ConfigManageContextOptions{
+		Args: []string{"--config=" + filepath.Join(dir, "app.json")},
+	})
+	ctx.Register("server", &server, func(err error) error { return err })
+	assert.Empty(t, ctx.Init())
+	assert.Equal(t, Server{Host: "fragment", Port: 8080}, server)
+}
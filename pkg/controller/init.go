@@ -7,37 +7,55 @@ import (
 	"github.com/SnowPhoenix0105/cfgm/internal/json2tree"
 	"github.com/SnowPhoenix0105/cfgm/internal/obj2tree"
 	"github.com/SnowPhoenix0105/cfgm/internal/property"
+	"github.com/SnowPhoenix0105/cfgm/internal/resolve"
+	"github.com/SnowPhoenix0105/cfgm/internal/toml2tree"
 	"github.com/SnowPhoenix0105/cfgm/internal/tree"
 	"github.com/SnowPhoenix0105/cfgm/internal/tree2obj"
-	"os"
-	"strings"
+	"github.com/SnowPhoenix0105/cfgm/internal/validate"
+	"github.com/SnowPhoenix0105/cfgm/internal/yaml2tree"
+	"io"
+	"io/fs"
+	"math"
+	"path/filepath"
 )
 
 const (
 	modifyTimeInvalid tree.ModifyTime = iota
 	modifyTimeBuild
-	modifyTimeMerge
 	modifyTimeEnv
 	modifyTimeCmd
 	modifyTimeAssign
+	// modifyTimeResolve is set to the nodes changed by the references, see resolve.Options.Time.
+	modifyTimeResolve
+
+	// modifyTimeMerge must be the last one, the i-th config file is merged with modifyTimeMerge + i.
+	modifyTimeMerge
+)
+
+const (
+	layerDefault = "default"
+	layerEnv     = "env"
+	layerCmd     = "cmd"
 )
 
-func (ctx *ConfigManageContext) addConfigObjectToTree(walker tree.Walker, configObject interface{}, path []string) error {
+const maxConfigFileCount = int(math.MaxInt8-modifyTimeMerge) + 1
+
+func (ctx *ConfigManageContext) addConfigObjectToTree(walker tree.Walker, configObject interface{}, path []string, options *obj2tree.Options) error {
 	for _, p := range path {
 		walker.EnterObj(p)
 	}
-	err := obj2tree.AppendTo(configObject, walker)
+	err := obj2tree.AppendTo(configObject, walker, options)
 	for range path {
 		walker.Exit()
 	}
 	return err
 }
 
-func (ctx *ConfigManageContext) buildTreeFromObjectConfig() bool {
+func (ctx *ConfigManageContext) buildTreeFromObjectConfig(root *tree.Node) bool {
 	ok := true
-	walker := tree.WriteFrom(ctx.root, modifyTimeBuild)
+	walker := tree.WriteFrom(root, modifyTimeBuild)
 	for i, item := range ctx.registerItems {
-		err := ctx.addConfigObjectToTree(walker, item.Obj, item.Path)
+		err := ctx.addConfigObjectToTree(walker, item.Obj, item.Path, ctx.options.buildOptions())
 		if err != nil {
 			ok = false
 			ctx.registerItems[i].Error = err
@@ -46,30 +64,148 @@ func (ctx *ConfigManageContext) buildTreeFromObjectConfig() bool {
 	return ok
 }
 
-func (ctx *ConfigManageContext) mergeTreeByFileConfig(filePath string) error {
-	if strings.HasSuffix(filePath, ".json") {
-		file, err := os.Open(filePath)
-		if err != nil {
-			return err
-		}
-		reader := bufio.NewReader(file)
-		return json2tree.Merge(ctx.root, reader, modifyTimeMerge)
+type mergeFunc func(root *tree.Node, reader io.RuneReader, time tree.ModifyTime, fileName string) error
+
+func (ctx *ConfigManageContext) mergeFuncFor(filePath string) mergeFunc {
+	switch filepath.Ext(filePath) {
+	case ".json":
+		return ctx.mergeJSON
+	case ".yaml", ".yml":
+		return yaml2tree.MergeWithFileName
+	case ".toml":
+		return toml2tree.MergeWithFileName
+	}
+	return nil
+}
+
+// mergeJSON merges a json file, which may include other files relative to it with "$include".
+func (ctx *ConfigManageContext) mergeJSON(root *tree.Node, reader io.RuneReader, time tree.ModifyTime, fileName string) error {
+	return json2tree.MergeWithOptions(root, reader, time, &json2tree.Options{
+		FileName: fileName,
+		Include:  ctx.includeFiles,
+		Open: func(name string) (io.ReadCloser, error) {
+			return ctx.fileSystem.Open(name)
+		},
+	})
+}
+
+func (ctx *ConfigManageContext) mergeTreeByFileConfig(root *tree.Node, filePath string, time tree.ModifyTime) error {
+	merge := ctx.mergeFuncFor(filePath)
+	if merge == nil {
+		return errors.New(fmt.Sprintf("unsupported file type :%s", filePath))
+	}
+	file, err := ctx.fileSystem.Open(filePath)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	reader := bufio.NewReader(file)
+	return merge(root, reader, time, filePath)
+}
+
+func (ctx *ConfigManageContext) parseCmd() ([]string, property.Record, error) {
+	return property.ParseFromCmd(ctx.args(), &property.CmdPropertyParseOptions{
+		ConfigFilePrefix: ctx.options.ConfigFilePathPrefix,
+		PropertyPrefix:   ctx.options.CommandLinePrefix,
+	})
+}
+
+func (ctx *ConfigManageContext) fixTreeByEnv(root *tree.Node) error {
+	record, err := property.ParseFromEnv(ctx.options.Environ(), &property.EnvPropertyParseOptions{
+		Prefix:    ctx.options.EnvPrefix,
+		Separator: ctx.options.EnvSeparator,
+	}, root)
+	if err != nil {
+		return err
 	}
-	return errors.New(fmt.Sprintf("unsupported file type :%s", filePath))
+	return property.FixTree(record, root, modifyTimeEnv)
+}
+
+func (ctx *ConfigManageContext) fixTree(root *tree.Node, record property.Record) error {
+	return property.FixTree(record, root, modifyTimeCmd)
 }
 
-func (ctx *ConfigManageContext) parseCmd() (string, property.Record, error) {
-	return property.ParseFromCmd(os.Args[1:], &property.CmdPropertyParseOptions{
-		ConfigFilePrefix: "--config=",
-		PropertyPrefix:   "-D",
+/*
+layerObserver is called after each layer is applied to root, the nodes of the layer are the ones
+modified at time. The layer is "default", the path of a config file, "env" or "cmd".
+*/
+type layerObserver func(layer string, root *tree.Node, time tree.ModifyTime)
+
+/*
+applyLayers copies the tree built from config objects, and applies the config files,
+the environment variables and the command line config to the copy in order.
+*/
+func (ctx *ConfigManageContext) applyLayers(observe layerObserver) (*tree.Node, error) {
+	root := ctx.buildRoot.Copy(modifyTimeInvalid)
+	observe(layerDefault, root, modifyTimeBuild)
+	err := ctx.mergeTreeByFileConfigs(root, ctx.filePaths, observe)
+	if err != nil {
+		return nil, err
+	}
+	err = ctx.fixTreeByEnv(root)
+	if err != nil {
+		return nil, err
+	}
+	observe(layerEnv, root, modifyTimeEnv)
+	err = ctx.fixTree(root, ctx.record)
+	if err != nil {
+		return nil, err
+	}
+	observe(layerCmd, root, modifyTimeCmd)
+	return root, nil
+}
+
+func (ctx *ConfigManageContext) resolveReferences(root *tree.Node) error {
+	return resolve.Resolve(root, &resolve.Options{
+		Environ: ctx.options.Environ,
+		ReadFile: func(name string) ([]byte, error) {
+			return fs.ReadFile(ctx.fileSystem, name)
+		},
+		Time: modifyTimeResolve,
 	})
 }
 
-func (ctx *ConfigManageContext) fixTree(record property.Record) error {
-	return property.FixTree(record, ctx.root, modifyTimeCmd)
+// layerSnapshot is the copy of the tree after a layer is applied, see Explain.
+type layerSnapshot struct {
+	layer string
+	root  *tree.Node
+	time  tree.ModifyTime
 }
 
-func resolveItem(root *tree.Node, item *registerItem, ch chan<- error) {
+/*
+loadTree applies all layers, then checks the keys which are not declared by any config object,
+and resolves the references like "${env:NAME}" in the strings at last. The snapshots of the
+layers are returned too, so that Explain can tell the layers of the loaded tree.
+*/
+func (ctx *ConfigManageContext) loadTree() (*tree.Node, []*layerSnapshot, error) {
+	// the included files are watched even if the load fails, so that fixing them can be found
+	ctx.includedFiles = nil
+	layers := make([]*layerSnapshot, 0)
+	root, err := ctx.applyLayers(func(layer string, root *tree.Node, time tree.ModifyTime) {
+		layers = append(layers, &layerSnapshot{layer: layer, root: root.Copy(modifyTimeInvalid), time: time})
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	err = ctx.checkUnknownKeys(root)
+	if err != nil {
+		return nil, nil, err
+	}
+	err = ctx.resolveReferences(root)
+	if err != nil {
+		return nil, nil, err
+	}
+	return root, layers, nil
+}
+
+func (ctx *ConfigManageContext) setRoot(root *tree.Node, layers []*layerSnapshot) {
+	ctx.lock.Lock()
+	defer ctx.lock.Unlock()
+	ctx.root = root
+	ctx.layers = layers
+}
+
+func resolveItem(root *tree.Node, item *registerItem, buildTime tree.ModifyTime, options *tree2obj.Options, ch chan<- error) {
 	if item.Error != nil {
 		ch <- item.Callback(item.Error)
 		return
@@ -83,32 +219,36 @@ func resolveItem(root *tree.Node, item *registerItem, ch chan<- error) {
 			}
 		}
 	}
-	tree2obj.RefillFrom(walker, item.Obj, modifyTimeBuild)
-	ch <- item.Callback(nil)
+	options.Path = item.Path
+	if err := tree2obj.RefillFrom(walker, item.Obj, buildTime, options); err != nil {
+		// the mismatched values are left as they were, so the validation makes no sense
+		ch <- item.Callback(err)
+		return
+	}
+	ch <- item.Callback(validate.Validate(item.Obj, subtree(root, item.Path), item.Path, options.Naming))
 }
 
-func (ctx *ConfigManageContext) invokeCallbacks(e error) []error {
+func (ctx *ConfigManageContext) invokeCallbacks(items []*registerItem, root *tree.Node, buildTime tree.ModifyTime) []error {
 	ch := make(chan error, 1)
-	if e != nil {
-		for i := range ctx.registerItems {
-			ctx.registerItems[i].Error = e
-		}
-	}
 
 	// because resolveItem() only read the AST, so it's ok to invoke it in parallel
-	for i := range ctx.registerItems {
-		go resolveItem(ctx.root, &ctx.registerItems[i], ch)
+	for _, item := range items {
+		go resolveItem(root, item, buildTime, ctx.options.refillOptions(), ch)
 	}
 
 	// join all goroutines and build the result
 	errs := make(map[error]struct{}, 0)
-	for range ctx.registerItems {
+	for range items {
 		err := <-ch
 		if err == nil {
 			continue
 		}
 		errs[err] = struct{}{}
 	}
+	return errorList(errs)
+}
+
+func errorList(errs map[error]struct{}) []error {
 	ret := make([]error, 0)
 	for err := range errs {
 		ret = append(ret, err)
@@ -116,24 +256,79 @@ func (ctx *ConfigManageContext) invokeCallbacks(e error) []error {
 	return ret
 }
 
+func (ctx *ConfigManageContext) allItems() []*registerItem {
+	items := make([]*registerItem, len(ctx.registerItems))
+	for i := range ctx.registerItems {
+		items[i] = &ctx.registerItems[i]
+	}
+	return items
+}
+
+/*
+failAll passes e to the callbacks of all config objects, except the ones failed to build, which
+receive their own errors. The error e is not bound to any config object but kept as the load error,
+so that a successful Reload() can still refill the config objects.
+*/
+func (ctx *ConfigManageContext) failAll(e error) []error {
+	ctx.loadError = e
+	errs := make(map[error]struct{}, 0)
+	for _, item := range ctx.allItems() {
+		err := item.Error
+		if err == nil {
+			err = e
+		}
+		if err = item.Callback(err); err != nil {
+			errs[err] = struct{}{}
+		}
+	}
+	return errorList(errs)
+}
+
+/*
+Init builds the config tree from the registered config objects, merges the config files, the
+environment variables and the command line config into it, then refills the config objects and
+invokes their callbacks. The RefillError is passed to the callbacks when some values mismatch the
+types of fields, see ConfigManageContextOptions.TypeMatching. Otherwise the config objects are
+validated with the rules in their "cfgm" tags, and the ValidationError is passed to the callbacks.
+
+When the command line contains the template flag ("--cfgm-template[=path]"), Init writes the
+config template instead and exits the process, the level of the template can be given by
+"--cfgm-template-level=level", see DumpTemplateWithLevel.
+*/
 func (ctx *ConfigManageContext) Init() []error {
-	ok := ctx.buildTreeFromObjectConfig()
+	ctx.loadLock.Lock()
+	defer ctx.loadLock.Unlock()
+
+	root := tree.NewNode()
+	ok := ctx.buildTreeFromObjectConfig(root)
+	ctx.setRoot(root, nil)
 	if !ok {
-		return ctx.invokeCallbacks(nil)
-	}
-	filePath, record, err := ctx.parseCmd()
-	if err != nil {
-		return ctx.invokeCallbacks(err)
+		return ctx.invokeCallbacks(ctx.allItems(), root, modifyTimeBuild)
 	}
-	if len(filePath) != 0 {
-		err = ctx.mergeTreeByFileConfig(filePath)
-		if err != nil {
-			return ctx.invokeCallbacks(err)
+	ctx.lock.Lock()
+	ctx.buildRoot = root.Copy(modifyTimeInvalid)
+	ctx.lock.Unlock()
+	ctx.loadError = nil
+
+	if path, level, ok := parseTemplateFlag(ctx.args()); ok {
+		if err := ctx.writeTemplate(path, level); err != nil {
+			return ctx.failAll(err)
 		}
+		exit(0)
+		return nil
 	}
-	err = ctx.fixTree(record)
+
+	filePaths, record, err := ctx.parseCmd()
+	if err != nil {
+		return ctx.failAll(err)
+	}
+	ctx.filePaths = filePaths
+	ctx.record = record
+
+	root, layers, err := ctx.loadTree()
 	if err != nil {
-		return ctx.invokeCallbacks(err)
+		return ctx.failAll(err)
 	}
-	return ctx.invokeCallbacks(nil)
+	ctx.setRoot(root, layers)
+	return ctx.invokeCallbacks(ctx.allItems(), root, modifyTimeBuild)
 }
@@ -1,6 +1,111 @@
 package controller
 
+import (
+	"github.com/SnowPhoenix0105/cfgm/internal/fields"
+	"github.com/SnowPhoenix0105/cfgm/internal/obj2tree"
+	"github.com/SnowPhoenix0105/cfgm/internal/tree2obj"
+	"io/fs"
+)
+
+type TypeMatching int
+
+const (
+	// TypeMatchingStrict reports a MismatchError when the type of value mismatches the field,
+	// e.g. "8080" for an int field.
+	TypeMatchingStrict TypeMatching = iota
+	// TypeMatchingLenient converts the scalars to the types of fields when possible,
+	// e.g. "8080" to 8080, 1 to true and 3.14 to "3.14".
+	TypeMatchingLenient
+)
+
+type Naming int
+
+const (
+	// NamingExact uses the names of fields as the keys, e.g. "PoolSize".
+	NamingExact Naming = iota
+	// NamingSnakeCase converts the names of fields to snake_case, e.g. "pool_size".
+	NamingSnakeCase
+	// NamingKebabCase converts the names of fields to kebab-case, e.g. "pool-size".
+	NamingKebabCase
+	// NamingCamelCase converts the names of fields to camelCase, e.g. "poolSize".
+	NamingCamelCase
+)
+
+func (options *ConfigManageContextOptions) naming() fields.Naming {
+	switch options.Naming {
+	case NamingSnakeCase:
+		return fields.NamingSnakeCase
+	case NamingKebabCase:
+		return fields.NamingKebabCase
+	case NamingCamelCase:
+		return fields.NamingCamelCase
+	}
+	return fields.NamingExact
+}
+
+func (options *ConfigManageContextOptions) buildOptions() *obj2tree.Options {
+	return &obj2tree.Options{Naming: options.naming()}
+}
+
+func (options *ConfigManageContextOptions) refillOptions() *tree2obj.Options {
+	if options.TypeMatching == TypeMatchingLenient {
+		return &tree2obj.Options{Mode: tree2obj.ModeLenient, Naming: options.naming()}
+	}
+	return &tree2obj.Options{Mode: tree2obj.ModeStrict, Naming: options.naming()}
+}
+
 type ConfigManageContextOptions struct {
-	CommandLinePrefix    string
+	// CommandLinePrefix is the prefix of the command line config, "-D" by default,
+	// e.g. "-Ddb.pool.size=16".
+	CommandLinePrefix string
+
+	// ConfigFilePathPrefix is the prefix of the config file paths, "--config=" by default,
+	// e.g. "--config=config.json".
 	ConfigFilePathPrefix string
+
+	// Args is the command line arguments without the program name, os.Args[1:] by default.
+	Args []string
+
+	// Environ returns the environment variables as "key=value", os.Environ by default.
+	Environ func() []string
+
+	// FS is the file system to read the config files from, the file system of the operating
+	// system by default. The paths in the command line are passed to it directly, so they must
+	// be valid paths of it, see fs.ValidPath.
+	FS fs.FS
+
+	// EnvPrefix enables the environment variable config when it is not empty,
+	// e.g. "APP" for variables like "APP_DB_POOL_SIZE".
+	EnvPrefix string
+
+	// EnvSeparator separates the parts of an environment variable name, "_" by default.
+	EnvSeparator string
+
+	// UnknownKeyPolicy decides how to treat the keys from the config file and the command line
+	// which are not declared by any config object, UnknownKeyPolicyWarn by default.
+	//
+	// Behaviour change: the unknown keys were ignored silently before, and they are passed to Warn
+	// by default now, which writes them to the standard error. Use UnknownKeyPolicyIgnore to keep
+	// the former behaviour.
+	UnknownKeyPolicy UnknownKeyPolicy
+
+	// TypeMatching decides whether a value can be refilled to a field of another type,
+	// TypeMatchingStrict by default. It can be overridden by `cfgm:"strict"` or `cfgm:"lenient"`
+	// of a field.
+	//
+	// Breaking change: the mismatched values, e.g. "8080" for an int field, were ignored silently
+	// before, and they are reported as MismatchError by default now, so Init() may fail with the
+	// configs which worked. Use TypeMatchingLenient to convert them instead.
+	TypeMatching TypeMatching
+
+	// Naming converts the names of fields to the keys of config, NamingExact by default, e.g.
+	// NamingSnakeCase for "pool_size" of the field PoolSize. It applies to the config files, the
+	// command line, the environment variables and the templates, and it can be overridden by the
+	// name in the "cfgm" tag of a field, e.g. `cfgm:"size"`. The fields tagged `cfgm:"-"` are skipped.
+	Naming Naming
+
+	// Warn receives the warnings, e.g. UnknownKeyError, which are written to the standard error
+	// by default. Set it to route the warnings to a logger, or to a function doing nothing to
+	// drop them.
+	Warn func(err error)
 }
@@ -0,0 +1,136 @@
+package controller
+
+import (
+	"github.com/SnowPhoenix0105/cfgm/internal/tree"
+	"time"
+)
+
+/*
+subtree finds the node at path, a part like "[0]" means the index of a list, nil will be returned
+if not found.
+*/
+func subtree(root *tree.Node, path []string) *tree.Node {
+	node := root
+	for _, p := range path {
+		if index, ok := parseIndex(p); ok && node.Has(tree.NodeKeyList) {
+			list := node.List()
+			if index >= len(list) {
+				return nil
+			}
+			node = list[index]
+			continue
+		}
+		if !node.Has(tree.NodeKeyObj) {
+			return nil
+		}
+		next, ok := node.Obj()[p]
+		if !ok {
+			return nil
+		}
+		node = next
+	}
+	return node
+}
+
+func subtreeChanged(oldRoot, newRoot *tree.Node, path []string) bool {
+	oldNode := subtree(oldRoot, path)
+	newNode := subtree(newRoot, path)
+	if oldNode == nil || newNode == nil {
+		return oldNode != newNode
+	}
+	return !tree.Equals(oldNode, newNode)
+}
+
+/*
+Reload reads the config files and the command line config again, and rebuilds the config tree.
+Only the config objects whose subtree has been changed will be refilled, and only their
+callbacks will be invoked.
+
+When the config file cannot be loaded, the current config will be kept, and the error will be
+returned without invoking any callback. When Init() failed to load the config, e.g. because of a
+broken config file, the command line is parsed again and all the config objects are refilled once
+the config is loaded.
+
+Reload is safe to be called concurrently, e.g. by Watch() and manually, and the reloads are
+applied one by one.
+
+The config objects are refilled in place, so reading them while Reload is running is a data race.
+The callers must synchronize the reads by themselves, e.g. read the config objects only in their
+callbacks, or copy them in the callbacks under a lock of their own. The callbacks are invoked
+while the load is locked, so calling Reload() or Init() in a callback blocks forever.
+*/
+func (ctx *ConfigManageContext) Reload() []error {
+	ctx.loadLock.Lock()
+	defer ctx.loadLock.Unlock()
+
+	if ctx.buildRoot == nil {
+		return []error{&NotInitializedError{Operation: "reload"}}
+	}
+	if ctx.loadError != nil {
+		filePaths, record, err := ctx.parseCmd()
+		if err != nil {
+			return []error{err}
+		}
+		ctx.filePaths = filePaths
+		ctx.record = record
+	}
+	root, layers, err := ctx.loadTree()
+	if err != nil {
+		return []error{err}
+	}
+
+	ctx.lock.RLock()
+	oldRoot := ctx.root
+	ctx.lock.RUnlock()
+
+	items := make([]*registerItem, 0)
+	for i, item := range ctx.registerItems {
+		if item.Error != nil {
+			continue
+		}
+		if ctx.loadError != nil || subtreeChanged(oldRoot, root, item.Path) {
+			items = append(items, &ctx.registerItems[i])
+		}
+	}
+	ctx.loadError = nil
+	ctx.setRoot(root, layers)
+	return ctx.invokeCallbacks(items, root, modifyTimeInvalid)
+}
+
+/*
+Watch polls the modify time of the config files with the given interval, and calls Reload()
+when any file has been changed, added or removed. The errors returned by Reload() will be passed to handler if
+handler is not nil. The files included by "$include" in the last load are watched too.
+
+Watch should be called after Init(). The config file paths are read again at each tick, so the
+paths parsed by the latest Init() or Reload() are watched, and nothing is watched before Init().
+
+The returned function stops the watching.
+*/
+func (ctx *ConfigManageContext) Watch(interval time.Duration, handler func(errs []error)) (stop func()) {
+	done := make(chan struct{})
+	last := ctx.statConfigFiles()
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+			}
+			current := ctx.statConfigFiles()
+			if sameFileStats(current, last) {
+				continue
+			}
+			last = current
+			errs := ctx.Reload()
+			if handler != nil && len(errs) != 0 {
+				handler(errs)
+			}
+		}
+	}()
+	return func() {
+		close(done)
+	}
+}
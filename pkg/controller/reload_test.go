@@ -0,0 +1,212 @@
+package controller
+
+import (
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestReload(t *testing.T) {
+	type Log struct {
+		Level string
+	}
+	type Server struct {
+		Port    int
+		Timeout int
+	}
+	dir, err := ioutil.TempDir("", "cfgm")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+	filePath := filepath.Join(dir, "config.json")
+	assert.Nil(t, ioutil.WriteFile(filePath, []byte(`{"log": {"Level": "info"}, "server": {"Port": 80}}`), 0644))
+
+	log := Log{Level: "debug"}
+	server := Server{Port: 8080, Timeout: 30}
+	logCount, serverCount := 0, 0
+	ctx := NewConfigManageContext(&ConfigManageContextOptions{Args: []string{"--config=" + filePath}})
+	ctx.Register("log", &log, func(err error) error {
+		logCount++
+		return err
+	})
+	ctx.Register("server", &server, func(err error) error {
+		serverCount++
+		return err
+	})
+	assert.Empty(t, ctx.Init())
+	assert.Equal(t, "info", log.Level)
+	assert.Equal(t, Server{Port: 80, Timeout: 30}, server)
+	assert.Equal(t, 1, logCount)
+	assert.Equal(t, 1, serverCount)
+
+	assert.Nil(t, ioutil.WriteFile(filePath, []byte(`{"log": {"Level": "warn"}, "server": {"Port": 80}}`), 0644))
+	assert.Empty(t, ctx.Reload())
+	assert.Equal(t, "warn", log.Level)
+	assert.Equal(t, 2, logCount)
+	assert.Equal(t, 1, serverCount)
+
+	// removed value falls back to the default one
+	assert.Nil(t, ioutil.WriteFile(filePath, []byte(`{"log": {"Level": "warn"}}`), 0644))
+	assert.Empty(t, ctx.Reload())
+	assert.Equal(t, Server{Port: 8080, Timeout: 30}, server)
+	assert.Equal(t, 2, logCount)
+	assert.Equal(t, 2, serverCount)
+
+	// broken file keeps the current config
+	assert.Nil(t, ioutil.WriteFile(filePath, []byte(`{"log": {"Level": }}`), 0644))
+	assert.NotEmpty(t, ctx.Reload())
+	assert.Equal(t, "warn", log.Level)
+	assert.Equal(t, 2, logCount)
+}
+
+func TestReload_AfterFailedInit(t *testing.T) {
+	type Server struct {
+		Port    int
+		Timeout int
+	}
+	dir, err := ioutil.TempDir("", "cfgm")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+	filePath := filepath.Join(dir, "config.json")
+	assert.Nil(t, ioutil.WriteFile(filePath, []byte(`{"server": {"Port": }}`), 0644))
+
+	server := Server{Port: 8080}
+	var received error
+	ctx := NewConfigManageContext(&ConfigManageContextOptions{
+		Args: []string{"--config=" + filePath, "-Dserver.Timeout=30"},
+	})
+	ctx.Register("server", &server, func(err error) error {
+		received = err
+		return err
+	})
+	assert.Len(t, ctx.Init(), 1)
+	assert.NotNil(t, received)
+	assert.Equal(t, Server{Port: 8080}, server)
+
+	// the broken file is still broken
+	received = nil
+	assert.Len(t, ctx.Reload(), 1)
+	assert.Nil(t, received)
+
+	// all the config objects are refilled, even if the subtree is the same as the default one
+	assert.Nil(t, ioutil.WriteFile(filePath, []byte(`{"server": {"Port": 8080}}`), 0644))
+	received = errors.New("not called")
+	assert.Empty(t, ctx.Reload())
+	assert.Nil(t, received)
+	assert.Equal(t, Server{Port: 8080, Timeout: 30}, server)
+
+	// the following reloads refill the changed ones only
+	received = errors.New("not called")
+	assert.Empty(t, ctx.Reload())
+	assert.NotNil(t, received)
+}
+
+func TestReload_Concurrent(t *testing.T) {
+	type Server struct {
+		Port int
+	}
+	// every load reads a different port, so that every reload refills the object
+	loadCount := int64(0)
+	environ := func() []string {
+		port := 8080 + atomic.AddInt64(&loadCount, 1)
+		return []string{"APP_SERVER_PORT=" + strconv.FormatInt(port, 10)}
+	}
+
+	server := Server{}
+	callbackCount := 0
+	ctx := NewConfigManageContext(&ConfigManageContextOptions{Environ: environ, EnvPrefix: "APP"})
+	ctx.Register("server", &server, func(err error) error {
+		callbackCount++
+		return err
+	})
+	assert.Empty(t, ctx.Init())
+
+	// the reloads race with each other without serializing, see go test -race
+	wg := sync.WaitGroup{}
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 8; j++ {
+				ctx.Reload()
+			}
+		}()
+	}
+	wg.Wait()
+
+	assert.Equal(t, Server{Port: 8080 + 33}, server)
+	assert.Equal(t, 33, callbackCount)
+}
+
+func TestWatch_IncludedFiles(t *testing.T) {
+	type DB struct {
+		Host string
+	}
+	dir, err := ioutil.TempDir("", "cfgm")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+	filePath := filepath.Join(dir, "config.json")
+	fragmentPath := filepath.Join(dir, "db.json")
+	assert.Nil(t, ioutil.WriteFile(filePath, []byte(`{"$include": "db.json"}`), 0644))
+	assert.Nil(t, ioutil.WriteFile(fragmentPath, []byte(`{"db": {"Host": "localhost"}}`), 0644))
+
+	db := DB{}
+	hosts := make(chan string, 8)
+	ctx := NewConfigManageContext(&ConfigManageContextOptions{Args: []string{"--config=" + filePath}})
+	ctx.Register("db", &db, func(err error) error {
+		hosts <- db.Host
+		return err
+	})
+	assert.Empty(t, ctx.Init())
+	assert.Equal(t, "localhost", <-hosts)
+
+	stop := ctx.Watch(10*time.Millisecond, nil)
+	defer stop()
+	assert.Nil(t, ioutil.WriteFile(fragmentPath, []byte(`{"db": {"Host": "db.example.com"}}`), 0644))
+	select {
+	case host := <-hosts:
+		assert.Equal(t, "db.example.com", host)
+	case <-time.After(5 * time.Second):
+		assert.Fail(t, "the change of the included file is not found")
+	}
+}
+
+func TestWatch_BeforeInit(t *testing.T) {
+	type Server struct {
+		Port int
+	}
+	dir, err := ioutil.TempDir("", "cfgm")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+	filePath := filepath.Join(dir, "config.json")
+	assert.Nil(t, ioutil.WriteFile(filePath, []byte(`{"server": {"Port": 80}}`), 0644))
+
+	server := Server{}
+	ports := make(chan int, 8)
+	ctx := NewConfigManageContext(&ConfigManageContextOptions{Args: []string{"--config=" + filePath}})
+	ctx.Register("server", &server, func(err error) error {
+		ports <- server.Port
+		return err
+	})
+
+	// the paths parsed by Init() are watched
+	stop := ctx.Watch(10*time.Millisecond, nil)
+	defer stop()
+	assert.Empty(t, ctx.Init())
+	assert.Equal(t, 80, <-ports)
+
+	time.Sleep(50 * time.Millisecond)
+	assert.Nil(t, ioutil.WriteFile(filePath, []byte(`{"server": {"Port": 8080}}`), 0644))
+	select {
+	case port := <-ports:
+		assert.Equal(t, 8080, port)
+	case <-time.After(5 * time.Second):
+		assert.Fail(t, "the change of the config file is not found")
+	}
+}
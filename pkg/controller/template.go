@@ -0,0 +1,148 @@
+package controller
+
+import (
+	"github.com/SnowPhoenix0105/cfgm/internal/tree"
+	"github.com/SnowPhoenix0105/cfgm/internal/tree2json"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	TemplateFormatJSON = "json"
+
+	templateFlag      = "--cfgm-template"
+	templateLevelFlag = "--cfgm-template-level"
+)
+
+// exit is replaced in tests, so that the template flag can be tested without exiting.
+var exit = os.Exit
+
+/*
+DumpTemplate writes the config template built from the registered config objects to writer.
+The template contains the default values, the "desc" tags as comments, and the prototypes of
+maps and slices as commented-out entries. The values of the fields tagged with `secret:"true"` are
+redacted as "******".
+
+Supported:
+
+	TemplateFormatJSON ("json")
+
+The template is built from the registered config objects if Init() has not been called, so that
+it can be dumped before loading the config.
+
+Errors:
+
+	UnsupportedFormatError: format is not supported
+	the errors of building the tree from the config objects
+	other errors returned by writer
+*/
+func (ctx *ConfigManageContext) DumpTemplate(writer io.Writer, format string) error {
+	return ctx.DumpTemplateWithLevel(writer, format, "")
+}
+
+/*
+DumpTemplateWithLevel is like DumpTemplate, but only the fields up to level are dumped, and the
+others are commented out. The level of a field is given by its "level" tag, which is one of
+"required", "common" and "advanced", and the fields without the tag inherit the level of their
+parents, "common" for the registered config objects. An empty level means all fields.
+
+Errors:
+
+	InvalidLevelError: level is not supported
+	the errors of DumpTemplate
+*/
+func (ctx *ConfigManageContext) DumpTemplateWithLevel(writer io.Writer, format string, level string) error {
+	options := &tree2json.DumpOptions{Level: tree.LevelNone}
+	if len(level) != 0 {
+		var ok bool
+		options.Level, ok = tree.ParseLevel(level)
+		if !ok {
+			return &InvalidLevelError{Level: level}
+		}
+	}
+	switch format {
+	case TemplateFormatJSON:
+		root, err := ctx.templateRoot()
+		if err != nil {
+			return err
+		}
+		_, err = tree2json.DumpToWriterWithOptions(root, writer, options)
+		return err
+	}
+	return &UnsupportedFormatError{Format: format}
+}
+
+/*
+templateRoot returns the tree built from the config objects by Init(), or builds one from the
+registered config objects if Init() has not built it. The prototypes are kept in the config objects
+then, so that Init() can still build them.
+*/
+func (ctx *ConfigManageContext) templateRoot() (*tree.Node, error) {
+	ctx.lock.RLock()
+	root := ctx.buildRoot
+	ctx.lock.RUnlock()
+	if root != nil {
+		return root, nil
+	}
+	root = tree.NewNode()
+	walker := tree.WriteFrom(root, modifyTimeBuild)
+	options := ctx.options.buildOptions()
+	options.KeepPrototypes = true
+	for _, item := range ctx.registerItems {
+		if err := ctx.addConfigObjectToTree(walker, item.Obj, item.Path, options); err != nil {
+			return nil, err
+		}
+	}
+	return root, nil
+}
+
+/*
+parseTemplateFlag finds the template flag from args. The flag may be "--cfgm-template" which
+means writing to the standard output, or "--cfgm-template=path" which means writing to the file.
+The level of the template can be given by "--cfgm-template-level=level".
+*/
+func parseTemplateFlag(args []string) (path string, level string, ok bool) {
+	for _, arg := range args {
+		if strings.HasPrefix(arg, templateLevelFlag+"=") {
+			level = strings.TrimPrefix(arg, templateLevelFlag+"=")
+		}
+	}
+	for _, arg := range args {
+		if arg == templateFlag {
+			return "", level, true
+		}
+		if strings.HasPrefix(arg, templateFlag+"=") {
+			return strings.TrimPrefix(arg, templateFlag+"="), level, true
+		}
+	}
+	return "", "", false
+}
+
+func templateFormatFor(path string) string {
+	ext := filepath.Ext(path)
+	if len(ext) == 0 {
+		return TemplateFormatJSON
+	}
+	return ext[1:]
+}
+
+func (ctx *ConfigManageContext) writeTemplate(path string, level string) error {
+	if len(path) == 0 {
+		return ctx.DumpTemplateWithLevel(os.Stdout, TemplateFormatJSON, level)
+	}
+	format := templateFormatFor(path)
+	if format != TemplateFormatJSON {
+		return &UnsupportedFormatError{Format: format}
+	}
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	err = ctx.DumpTemplateWithLevel(file, format, level)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
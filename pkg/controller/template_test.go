@@ -0,0 +1,118 @@
+package controller
+
+import (
+	"bytes"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestDumpTemplate(t *testing.T) {
+	type Server struct {
+		Host  string            `desc:"the host to listen"`
+		Port  int               `desc:"the port to listen"`
+		Hosts map[string]string `desc:"the virtual hosts"`
+	}
+	dir, err := ioutil.TempDir("", "cfgm")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+	filePath := filepath.Join(dir, "template.json")
+
+	exitCode := -1
+	exit = func(code int) { exitCode = code }
+	defer func() { exit = os.Exit }()
+
+	server := Server{
+		Host: "localhost",
+		Port: 8080,
+		Hosts: map[string]string{
+			"__prototype__": "/var/www",
+		},
+	}
+	callbackCount := 0
+	options := &ConfigManageContextOptions{Args: []string{"--cfgm-template=" + filePath, "-Dserver.Port=80"}}
+	ctx := NewConfigManageContext(options)
+	ctx.Register("server", &server, func(err error) error {
+		callbackCount++
+		return err
+	})
+	// the template can be dumped before Init()
+	before := bytes.Buffer{}
+	assert.Nil(t, ctx.DumpTemplate(&before, TemplateFormatJSON))
+
+	assert.Empty(t, ctx.Init())
+	assert.Equal(t, 0, exitCode)
+	assert.Equal(t, 0, callbackCount)
+	content, err := ioutil.ReadFile(filePath)
+	assert.Nil(t, err)
+	template := string(content)
+	assert.Contains(t, template, "// the port to listen")
+	assert.Contains(t, template, `"Port": 8080`)
+	assert.Contains(t, template, `// "Key": "/var/www",`)
+	assert.Equal(t, template, before.String())
+
+	buf := bytes.Buffer{}
+	assert.Nil(t, ctx.DumpTemplate(&buf, TemplateFormatJSON))
+	assert.Equal(t, template, buf.String())
+	assert.IsType(t, &UnsupportedFormatError{}, ctx.DumpTemplate(&buf, "xml"))
+
+	options.Args = []string{"--cfgm-template=" + filepath.Join(dir, "template.xml")}
+	exitCode = -1
+	errs := ctx.Init()
+	assert.Equal(t, -1, exitCode)
+	assert.Len(t, errs, 1)
+	assert.IsType(t, &UnsupportedFormatError{}, errs[0])
+}
+
+func TestDumpTemplateWithLevel(t *testing.T) {
+	type Server struct {
+		Host    string `level:"required"`
+		Port    int
+		Timeout int `level:"advanced"`
+	}
+	buf := bytes.Buffer{}
+	exitCode := -1
+	exit = func(code int) { exitCode = code }
+	defer func() { exit = os.Exit }()
+
+	server := Server{}
+	ctx := NewConfigManageContext(&ConfigManageContextOptions{
+		Args: []string{"--cfgm-template-level=common", "--cfgm-template"},
+	})
+	ctx.Register("server", &server, func(err error) error { return err })
+	assert.Empty(t, ctx.Init())
+	assert.Equal(t, 0, exitCode)
+
+	assert.Nil(t, ctx.DumpTemplateWithLevel(&buf, TemplateFormatJSON, "required"))
+	template := buf.String()
+	assert.Contains(t, template, `"Host": ""`)
+	assert.Contains(t, template, `// "Port": 0,`)
+	assert.Contains(t, template, `// "Timeout": 0,`)
+
+	buf.Reset()
+	assert.Nil(t, ctx.DumpTemplateWithLevel(&buf, TemplateFormatJSON, "common"))
+	template = buf.String()
+	assert.Contains(t, template, `"Port": 0`)
+	assert.NotContains(t, template, `// "Port": 0,`)
+	assert.Contains(t, template, `// "Timeout": 0,`)
+
+	assert.Equal(t, &InvalidLevelError{Level: "all"}, ctx.DumpTemplateWithLevel(&buf, TemplateFormatJSON, "all"))
+}
+
+func TestDumpTemplate_NilInterface(t *testing.T) {
+	type Plugin struct {
+		Any     interface{}
+		Options map[string]interface{}
+	}
+	ctx := NewConfigManageContext(&ConfigManageContextOptions{Args: []string{}})
+	ctx.Register("plugin", &Plugin{}, func(err error) error { return err })
+	assert.Empty(t, ctx.Init())
+
+	buf := bytes.Buffer{}
+	assert.Nil(t, ctx.DumpTemplate(&buf, TemplateFormatJSON))
+	template := buf.String()
+	assert.Contains(t, template, `"Any": null,`)
+	assert.Contains(t, template, `// "Key": null,`)
+}
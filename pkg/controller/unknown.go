@@ -0,0 +1,169 @@
+package controller
+
+import (
+	"github.com/SnowPhoenix0105/cfgm/internal/tree"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+type UnknownKeyPolicy int
+
+const (
+	// UnknownKeyPolicyWarn passes the unknown keys to ConfigManageContextOptions.Warn.
+	UnknownKeyPolicyWarn UnknownKeyPolicy = iota
+	// UnknownKeyPolicyError makes Init() and Reload() fail with UnknownKeysError.
+	UnknownKeyPolicyError
+	// UnknownKeyPolicyIgnore ignores the unknown keys silently.
+	UnknownKeyPolicyIgnore
+)
+
+type unknownKeyEnv struct {
+	path []string
+	keys []*UnknownKeyError
+}
+
+/*
+findUnknownKeys compares the loaded tree with the tree built from config objects, and finds the
+keys which are not declared by any config object. The keys of maps are always known, and their
+values are compared with the prototype.
+*/
+func findUnknownKeys(buildRoot, root *tree.Node) []*UnknownKeyError {
+	env := unknownKeyEnv{
+		path: make([]string, 0),
+		keys: make([]*UnknownKeyError, 0),
+	}
+	env.compare(buildRoot, root)
+	sort.Slice(env.keys, func(i, j int) bool {
+		return env.keys[i].Path < env.keys[j].Path
+	})
+	return env.keys
+}
+
+func (env *unknownKeyEnv) compare(declared, loaded *tree.Node) {
+	if loaded.Has(tree.NodeKeyObj) {
+		env.compareObj(declared, loaded)
+	}
+	if loaded.Has(tree.NodeKeyList) {
+		env.compareList(declared, loaded)
+	}
+}
+
+func (env *unknownKeyEnv) compareObj(declared, loaded *tree.Node) {
+	isMap := declared.Has(tree.NodeKeyObjPrototype)
+	if !isMap && !declared.Has(tree.NodeKeyObj) {
+		// the type mismatch is not a problem of keys
+		return
+	}
+	declaredObj := declared.Obj()
+	for key, next := range loaded.Obj() {
+		declaredNext, ok := declaredObj[key]
+		if !ok && isMap {
+			declaredNext, ok = declared.ObjPrototype(), true
+		}
+		env.path = append(env.path, key)
+		if ok {
+			env.compare(declaredNext, next)
+		} else {
+			env.keys = append(env.keys, &UnknownKeyError{
+				Path:       strings.Join(env.path, "."),
+				Source:     next.Source(),
+				Suggestion: suggestKey(key, declaredObj),
+			})
+		}
+		env.path = env.path[:len(env.path)-1]
+	}
+}
+
+func (env *unknownKeyEnv) compareList(declared, loaded *tree.Node) {
+	if !declared.Has(tree.NodeKeyListPrototype) {
+		return
+	}
+	declaredList := declared.List()
+	for i, next := range loaded.List() {
+		declaredNext := declared.ListPrototype()
+		if i < len(declaredList) {
+			declaredNext = declaredList[i]
+		}
+		env.path = append(env.path, "["+strconv.Itoa(i)+"]")
+		env.compare(declaredNext, next)
+		env.path = env.path[:len(env.path)-1]
+	}
+}
+
+/*
+suggestKey finds the declared key which is the most similar to key, the letter case is ignored.
+An empty string will be returned when no declared key is similar enough.
+*/
+func suggestKey(key string, declared tree.NodeObj) string {
+	lowerKey := strings.ToLower(key)
+	maxDistance := len(lowerKey) / 3
+	if maxDistance < 2 {
+		maxDistance = 2
+	}
+	candidates := make([]string, 0, len(declared))
+	for candidate := range declared {
+		candidates = append(candidates, candidate)
+	}
+	sort.Strings(candidates)
+
+	suggestion := ""
+	for _, candidate := range candidates {
+		distance := editDistance(lowerKey, strings.ToLower(candidate))
+		if distance <= maxDistance && distance < len(lowerKey) {
+			suggestion = candidate
+			maxDistance = distance - 1
+		}
+	}
+	return suggestion
+}
+
+// editDistance is the Levenshtein distance between a and b.
+func editDistance(a, b string) int {
+	ra := []rune(a)
+	rb := []rune(b)
+	prev := make([]int, len(rb)+1)
+	curr := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		curr[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			curr[j] = minInt(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+	return prev[len(rb)]
+}
+
+func minInt(first int, others ...int) int {
+	ret := first
+	for _, value := range others {
+		if value < ret {
+			ret = value
+		}
+	}
+	return ret
+}
+
+func (ctx *ConfigManageContext) checkUnknownKeys(root *tree.Node) error {
+	if ctx.options.UnknownKeyPolicy == UnknownKeyPolicyIgnore {
+		return nil
+	}
+	keys := findUnknownKeys(ctx.buildRoot, root)
+	if len(keys) == 0 {
+		return nil
+	}
+	if ctx.options.UnknownKeyPolicy == UnknownKeyPolicyError {
+		return &UnknownKeysError{Keys: keys}
+	}
+	for _, key := range keys {
+		ctx.options.Warn(key)
+	}
+	return nil
+}
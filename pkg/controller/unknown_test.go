@@ -0,0 +1,83 @@
+package controller
+
+import (
+	"github.com/SnowPhoenix0105/cfgm/internal/tree"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSuggestKey(t *testing.T) {
+	declared := tree.NodeObj{
+		"Timeout": tree.NewNode(),
+		"Host":    tree.NewNode(),
+		"Port":    tree.NewNode(),
+	}
+	cases := map[string]string{
+		"Timout":  "Timeout",
+		"timeout": "Timeout",
+		"Hots":    "Host",
+		"port":    "Port",
+		"Name":    "",
+		"a":       "",
+	}
+	for key, expect := range cases {
+		assert.Equal(t, expect, suggestKey(key, declared), key)
+	}
+}
+
+func TestInit_UnknownKeys(t *testing.T) {
+	type Server struct {
+		Host    string
+		Port    int
+		Timeout int
+		Headers map[string]string
+		Routes  []struct {
+			Path string
+		}
+	}
+	dir, err := ioutil.TempDir("", "cfgm")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+	filePath := filepath.Join(dir, "config.json")
+	assert.Nil(t, ioutil.WriteFile(filePath, []byte(`{
+	"server": {
+		"Port": 80,
+		"Timout": 3,
+		"Headers": {"X-Any": "1"},
+		"Routes": [{"Path": "/"}, {"Pth": "/api"}]
+	},
+	"unknown": {}
+}`), 0644))
+
+	server := Server{}
+	warnings := make([]error, 0)
+	ctx := NewConfigManageContext(&ConfigManageContextOptions{
+		Args: []string{"--config=" + filePath, "-Dserver.Hots=remote"},
+		Warn: func(err error) {
+			warnings = append(warnings, err)
+		},
+	})
+	ctx.Register("server", &server, func(err error) error { return err })
+	assert.Empty(t, ctx.Init())
+	assert.Equal(t, 80, server.Port)
+	assert.Equal(t, []error{
+		&UnknownKeyError{Path: "server.Hots", Source: "-Dserver.Hots=remote", Suggestion: "Host"},
+		&UnknownKeyError{Path: "server.Routes.[1].Pth", Source: filePath + ":6:30", Suggestion: "Path"},
+		&UnknownKeyError{Path: "server.Timout", Source: filePath + ":4:3", Suggestion: "Timeout"},
+		&UnknownKeyError{Path: "unknown", Source: filePath + ":8:2", Suggestion: ""},
+	}, warnings)
+	assert.Equal(t, "cfgm: unknown key (server.Timout) from "+filePath+":4:3, did you mean (Timeout)?", warnings[2].Error())
+
+	ctx = NewConfigManageContext(&ConfigManageContextOptions{
+		Args:             []string{"--config=" + filePath, "-Dserver.Hots=remote"},
+		UnknownKeyPolicy: UnknownKeyPolicyError,
+	})
+	ctx.Register("server", &server, func(err error) error { return err })
+	errs := ctx.Init()
+	assert.Len(t, errs, 1)
+	assert.IsType(t, &UnknownKeysError{}, errs[0])
+	assert.Len(t, errs[0].(*UnknownKeysError).Keys, 4)
+}